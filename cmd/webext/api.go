@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/AdguardTeam/golibs/log"
+	"github.com/maximtop/extdash/internal/api"
+	"github.com/maximtop/extdash/internal/chrome"
+	"github.com/maximtop/extdash/internal/edge"
+	"github.com/maximtop/extdash/internal/firefox"
+	"github.com/maximtop/extdash/internal/notify"
+	"github.com/urfave/cli/v2"
+)
+
+// apiStatus returns the status of the extension in the store.
+func apiStatus(c *cli.Context, req *api.Request) (result any, err error) {
+	switch req.Store {
+	case "chrome":
+		store, storeV2, err := getChromeStore()
+		if err != nil {
+			return nil, fmt.Errorf("initializing chrome store: %w", err)
+		}
+
+		if storeV2 != nil {
+			return storeV2.Status(req.AppID)
+		}
+
+		return store.Versions(req.AppID)
+	case "firefox":
+		store, err := getFirefoxStore(c)
+		if err != nil {
+			return nil, fmt.Errorf("initializing firefox store: %w", err)
+		}
+
+		status, err := store.Status(req.AppID)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.RawMessage(status), nil
+	default:
+		return nil, fmt.Errorf("status of the extensions in %s isn't available", req.Store)
+	}
+}
+
+// apiUpload uploads the new version of the extension to the store.
+func apiUpload(c *cli.Context, req *api.Request) (result any, err error) {
+	switch req.Store {
+	case "chrome":
+		store, storeV2, err := getChromeStore()
+		if err != nil {
+			return nil, fmt.Errorf("initializing chrome store: %w", err)
+		}
+
+		if storeV2 != nil {
+			return storeV2.Upload(req.AppID, req.File)
+		}
+
+		return store.Update(req.AppID, req.File)
+	case "firefox":
+		store, err := getFirefoxStore(c)
+		if err != nil {
+			return nil, fmt.Errorf("initializing firefox store: %w", err)
+		}
+
+		return nil, store.Update(req.File, "")
+	case "edge":
+		store, err := getEdgeStore()
+		if err != nil {
+			return nil, fmt.Errorf("initializing edge store: %w", err)
+		}
+
+		return store.Update(req.AppID, req.File, edge.UpdateOptions{})
+	default:
+		return nil, fmt.Errorf("unknown store %q", req.Store)
+	}
+}
+
+// apiPublish publishes the uploaded version of the extension.
+func apiPublish(req *api.Request) (result any, err error) {
+	switch req.Store {
+	case "chrome":
+		store, storeV2, err := getChromeStore()
+		if err != nil {
+			return nil, fmt.Errorf("initializing chrome store: %w", err)
+		}
+
+		publishOptions := chrome.PublishOptions{Target: chrome.PublishTargetDefault}
+		if storeV2 != nil {
+			return storeV2.Publish(req.AppID, publishOptions)
+		}
+
+		return store.Publish(req.AppID, publishOptions)
+	case "edge":
+		store, err := getEdgeStore()
+		if err != nil {
+			return nil, fmt.Errorf("initializing edge store: %w", err)
+		}
+
+		return store.Publish(req.AppID)
+	default:
+		return nil, fmt.Errorf("publishing in %s isn't available, the uploaded version is published on review", req.Store)
+	}
+}
+
+// apiSign signs the extension in the store, the signed extension is saved to
+// the working directory of the server.
+func apiSign(c *cli.Context, req *api.Request) (result any, err error) {
+	if req.Store != "firefox" {
+		return nil, fmt.Errorf("signing in %s isn't available", req.Store)
+	}
+
+	store, err := getFirefoxStore(c)
+	if err != nil {
+		return nil, fmt.Errorf("initializing firefox store: %w", err)
+	}
+
+	return nil, store.Sign(req.File, firefox.SignOptions{})
+}
+
+// newAPIExecutor returns the executor of the API jobs, which uses the same
+// store clients as the commands.  The releases are recorded to the history and
+// sent to the notifier, if not nil.
+func newAPIExecutor(c *cli.Context, cfg *appConfig, notifier notify.Notifier) (executor api.Executor) {
+	historyPath := c.String("history")
+
+	return func(ctx context.Context, req *api.Request, logf api.Logf) (result any, err error) {
+		if req.Operation == api.OperationStatus {
+			return apiStatus(c, req)
+		}
+
+		manifest := readManifest(req.File)
+		appID := req.AppID
+		if appID == "" && req.Store == "firefox" {
+			appID = manifest.geckoID()
+		}
+
+		version := releaseVersion(historyPath, manifest, req.Store, appID)
+		if version != "" {
+			logf("%s of version %s in %s", req.Operation, version, req.Store)
+		} else {
+			logf("%s in %s", req.Operation, req.Store)
+		}
+
+		switch req.Operation {
+		case api.OperationUpload:
+			result, err = apiUpload(c, req)
+		case api.OperationPublish:
+			result, err = apiPublish(req)
+		case api.OperationSign:
+			result, err = apiSign(c, req)
+		default:
+			return nil, fmt.Errorf("unknown operation %q", req.Operation)
+		}
+
+		event := &notify.Event{
+			Time:      time.Now(),
+			Type:      notify.EventReleaseSucceeded,
+			Extension: cfg.extensionName(req.Store, appID),
+			Store:     req.Store,
+			AppID:     appID,
+			Version:   version,
+			Operation: string(req.Operation),
+		}
+
+		if err != nil {
+			// the failed store methods return typed nil pointers
+			result = nil
+			event.Type = notify.EventReleaseFailed
+			event.Message = err.Error()
+		}
+
+		saveRelease(historyPath, event, result)
+
+		if notifier != nil {
+			notifyErr := notifier.Notify(ctx, event)
+			if notifyErr != nil {
+				log.Error("sending release notification: %s", notifyErr)
+			}
+		}
+
+		return result, err
+	}
+}
@@ -0,0 +1,377 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/caarlos0/env/v6"
+	"github.com/maximtop/extdash/internal/chrome"
+	"github.com/maximtop/extdash/internal/edge"
+	"github.com/urfave/cli/v2"
+)
+
+// chromeTokenURL is the default address of the Google OAuth token endpoint,
+// CHROME_TOKEN_URL overrides it.
+const chromeTokenURL = "https://accounts.google.com/o/oauth2/token"
+
+// randomState returns a random value for the OAuth state parameter.
+func randomState() (state string, err error) {
+	buf := make([]byte, 16)
+
+	_, err = rand.Read(buf)
+	if err != nil {
+		return "", fmt.Errorf("reading random bytes: %w", err)
+	}
+
+	return hex.EncodeToString(buf), nil
+}
+
+// awaitAuthCode serves the OAuth redirect on the listener and returns the
+// authorization code received with the expected state.  The requests to other
+// paths and without the code or the error are answered with 404.
+func awaitAuthCode(listener net.Listener, state string, timeout time.Duration) (code string, err error) {
+	type result struct {
+		code string
+		err  error
+	}
+
+	results := make(chan result, 1)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		// ignore the requests made by the browser besides the redirect, e.g.
+		// the favicon fetch
+		if r.URL.Path != "/" || (!query.Has("code") && !query.Has("error")) {
+			http.NotFound(w, r)
+
+			return
+		}
+
+		var res result
+		switch {
+		case query.Get("state") != state:
+			res.err = fmt.Errorf("unexpected state %q", query.Get("state"))
+		case query.Get("error") != "":
+			res.err = fmt.Errorf("authorization failed: %s", query.Get("error"))
+		case query.Get("code") == "":
+			res.err = fmt.Errorf("no authorization code")
+		default:
+			res.code = query.Get("code")
+		}
+
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+		} else {
+			_, _ = fmt.Fprintln(w, "Authorization is completed, you can close this page.")
+		}
+
+		select {
+		case results <- res:
+		default:
+		}
+	})
+
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// buffered, so that the goroutine doesn't leak if the result is received
+	// first
+	serveErrs := make(chan error, 1)
+	go func() {
+		serveErr := server.Serve(listener)
+		if !errors.Is(serveErr, http.ErrServerClosed) {
+			serveErrs <- fmt.Errorf("serving redirect: %w", serveErr)
+		}
+	}()
+
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		err = errors.WithDeferred(err, server.Shutdown(ctx))
+	}()
+
+	select {
+	case res := <-results:
+		return res.code, res.err
+	case err = <-serveErrs:
+		return "", err
+	case <-time.After(timeout):
+		return "", fmt.Errorf("no redirect received in %s", timeout)
+	}
+}
+
+// setEnvFileValue sets the value of the variable in the dotenv file, keeping
+// the other lines intact.  The file is created if it doesn't exist.
+func setEnvFileValue(envFile, name, value string) (err error) {
+	content, err := os.ReadFile(filepath.Clean(envFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("reading %q: %w", envFile, err)
+	}
+
+	line := fmt.Sprintf("%s=%s", name, value)
+
+	var lines []string
+	found := false
+
+	scanner := bufio.NewScanner(strings.NewReader(string(content)))
+	for scanner.Scan() {
+		current := scanner.Text()
+		trimmed := strings.TrimPrefix(strings.TrimSpace(current), "export ")
+
+		if strings.HasPrefix(trimmed, name+"=") {
+			current = line
+			found = true
+		}
+
+		lines = append(lines, current)
+	}
+
+	if !found {
+		lines = append(lines, line)
+	}
+
+	err = os.WriteFile(filepath.Clean(envFile), []byte(strings.Join(lines, "\n")+"\n"), 0o600)
+	if err != nil {
+		return fmt.Errorf("writing %q: %w", envFile, err)
+	}
+
+	return nil
+}
+
+// Targets of the chrome refresh token selected by the save-to flag.
+const (
+	saveToEnv   = "env"
+	saveToVault = "vault"
+)
+
+// authChrome obtains the chrome refresh token with the OAuth authorization code
+// flow and saves it to the dotenv file or to the vault.
+func authChrome(c *cli.Context) (err error) {
+	switch saveTo := c.String("save-to"); saveTo {
+	case saveToEnv, saveToVault:
+		// go on
+	default:
+		return fmt.Errorf("unknown --save-to %q, use %s or %s", saveTo, saveToEnv, saveToVault)
+	}
+
+	type config struct {
+		ClientID     string `env:"CHROME_CLIENT_ID,notEmpty"`
+		ClientSecret string `env:"CHROME_CLIENT_SECRET,notEmpty"`
+		TokenURL     string `env:"CHROME_TOKEN_URL"`
+	}
+
+	err = credsVault.provide("CHROME_CLIENT_ID", "CHROME_CLIENT_SECRET")
+	if err != nil {
+		return err
+	}
+
+	cfg := config{}
+	if err = env.Parse(&cfg); err != nil {
+		return fmt.Errorf("failed to parse environment variables: %w", err)
+	}
+
+	if cfg.TokenURL == "" {
+		cfg.TokenURL = chromeTokenURL
+	}
+
+	client := chrome.Client{
+		URL:          cfg.TokenURL,
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", c.Int("port")))
+	if err != nil {
+		return fmt.Errorf("listening for redirect: %w", err)
+	}
+
+	redirectURI := fmt.Sprintf("http://%s/", listener.Addr())
+
+	state, err := randomState()
+	if err != nil {
+		return fmt.Errorf("generating state: %w", err)
+	}
+
+	codeVerifier, err := chrome.NewCodeVerifier()
+	if err != nil {
+		return fmt.Errorf("generating code verifier: %w", err)
+	}
+
+	fmt.Printf("Open the following URL in the browser and grant access:\n\n%s\n\n", client.AuthCodeURL(
+		chrome.ConsentURL,
+		redirectURI,
+		state,
+		codeVerifier,
+	))
+
+	code, err := awaitAuthCode(listener, state, c.Duration("timeout"))
+	if err != nil {
+		return fmt.Errorf("awaiting authorization code: %w", err)
+	}
+
+	tokens, err := client.ExchangeCode(code, codeVerifier, redirectURI)
+	if err != nil {
+		return fmt.Errorf("exchanging authorization code: %w", err)
+	}
+
+	target := c.String("env-file")
+	if c.String("save-to") == saveToVault {
+		target = c.String("vault")
+		err = setVaultValue(target, "CHROME_REFRESH_TOKEN", tokens.RefreshToken)
+	} else {
+		err = setEnvFileValue(target, "CHROME_REFRESH_TOKEN", tokens.RefreshToken)
+	}
+
+	if err != nil {
+		return fmt.Errorf("saving refresh token: %w", err)
+	}
+
+	fmt.Printf("CHROME_REFRESH_TOKEN is saved to %s\n", target)
+
+	return nil
+}
+
+// credentialsCheck describes the result of the store credentials check.
+type credentialsCheck struct {
+	identity string
+	scopes   []string
+}
+
+// checkChromeCredentials exchanges the chrome credentials for an access token
+// and retrieves the granted scopes.
+func checkChromeCredentials(_ *cli.Context) (result *credentialsCheck, err error) {
+	store, storeV2, err := getChromeStore()
+	if err != nil {
+		return nil, err
+	}
+
+	var client chrome.Authorizer
+	if store != nil {
+		client = store.Client
+	} else {
+		client = storeV2.Client
+	}
+
+	accessToken, err := client.Authorize()
+	if err != nil {
+		return nil, fmt.Errorf("getting access token: %w", err)
+	}
+
+	info, err := chrome.GetTokenInfo(chrome.TokenInfoURL, accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("getting token info: %w", err)
+	}
+
+	identity := info.Email
+	if identity == "" {
+		identity = "client " + info.AuthorizedParty
+	}
+
+	return &credentialsCheck{
+		identity: identity,
+		scopes:   strings.Fields(info.Scope),
+	}, nil
+}
+
+// checkFirefoxCredentials sends a signed request for the profile of the
+// account the firefox credentials belong to.
+func checkFirefoxCredentials(c *cli.Context) (result *credentialsCheck, err error) {
+	store, err := getFirefoxStore(c)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := store.Profile()
+	if err != nil {
+		return nil, fmt.Errorf("getting profile: %w", err)
+	}
+
+	return &credentialsCheck{
+		identity: fmt.Sprintf("%s (id %d)", profile.Username, profile.ID),
+	}, nil
+}
+
+// checkEdgeCredentials exchanges the edge credentials for an access token and
+// reads the granted roles from it.
+func checkEdgeCredentials(_ *cli.Context) (result *credentialsCheck, err error) {
+	store, err := getEdgeStore()
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := store.Client.Authorize()
+	if err != nil {
+		return nil, fmt.Errorf("getting access token: %w", err)
+	}
+
+	claims, err := edge.ParseTokenClaims(accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("reading access token: %w", err)
+	}
+
+	scopes := append(strings.Fields(claims.Scope), claims.Roles...)
+
+	return &credentialsCheck{
+		identity: fmt.Sprintf("app %s, tenant %s", claims.AppID, claims.TenantID),
+		scopes:   scopes,
+	}, nil
+}
+
+// credentialsCheckers contains the credentials check functions by store name.
+var credentialsCheckers = map[string]func(c *cli.Context) (*credentialsCheck, error){
+	"chrome":  checkChromeCredentials,
+	"firefox": checkFirefoxCredentials,
+	"edge":    checkEdgeCredentials,
+}
+
+// authCheck checks credentials of the stores passed as arguments, or of all
+// stores, and prints the results.
+func authCheck(c *cli.Context) (err error) {
+	stores := c.Args().Slice()
+	if len(stores) == 0 {
+		stores = []string{"chrome", "firefox", "edge"}
+	}
+
+	failed := 0
+
+	for _, store := range stores {
+		checker, ok := credentialsCheckers[store]
+		if !ok {
+			return fmt.Errorf("unknown store %q", store)
+		}
+
+		result, checkErr := checker(c)
+		if checkErr != nil {
+			failed++
+			fmt.Printf("%s: FAILED: %s\n", store, checkErr)
+
+			continue
+		}
+
+		fmt.Printf("%s: OK, identity: %s", store, result.identity)
+		if len(result.scopes) > 0 {
+			fmt.Printf(", scopes: %s", strings.Join(result.scopes, " "))
+		}
+		fmt.Println()
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d stores failed the check", failed, len(stores))
+	}
+
+	return nil
+}
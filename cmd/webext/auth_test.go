@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAwaitAuthCode(t *testing.T) {
+	const state = "state"
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	type result struct {
+		code string
+		err  error
+	}
+
+	results := make(chan result, 1)
+	go func() {
+		code, awaitErr := awaitAuthCode(listener, state, 5*time.Second)
+		results <- result{code: code, err: awaitErr}
+	}()
+
+	baseURL := "http://" + listener.Addr().String()
+	get := func(path string) (status int) {
+		resp, getErr := http.Get(baseURL + path)
+		require.NoError(t, getErr)
+		require.NoError(t, resp.Body.Close())
+
+		return resp.StatusCode
+	}
+
+	// the stray requests of the browser don't end the flow
+	assert.Equal(t, http.StatusNotFound, get("/favicon.ico"))
+	assert.Equal(t, http.StatusNotFound, get("/"))
+
+	assert.Equal(t, http.StatusOK, get("/?state="+state+"&code=test-code"))
+
+	res := <-results
+	require.NoError(t, res.err)
+	assert.Equal(t, "test-code", res.code)
+}
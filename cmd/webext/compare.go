@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/maximtop/extdash/internal/stats"
+	"github.com/maximtop/extdash/internal/watch"
+	"github.com/urfave/cli/v2"
+)
+
+// versionParts are the names of the version parts from the most significant
+// one, the fourth and the following parts are all build.
+var versionParts = []string{"major", "minor", "patch", "build"}
+
+// storeVersions are the versions of the extension in the store.
+type storeVersions struct {
+	Store          string `json:"store"`
+	AppID          string `json:"app_id"`
+	State          string `json:"state,omitempty"`
+	Version        string `json:"version,omitempty"`
+	PendingVersion string `json:"pending_version,omitempty"`
+	// Lag is the most significant part of the version, which is older than
+	// the newest one, e.g. "minor".  It is empty if the store serves the
+	// newest version.
+	Lag   string `json:"lag,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// extensionVersions are the versions of the extension in all stores.
+type extensionVersions struct {
+	Extension string `json:"extension"`
+	// Newest is the newest published version.
+	Newest string           `json:"newest,omitempty"`
+	Stores []*storeVersions `json:"stores"`
+}
+
+// lagPart returns the index of the most significant part of version, which
+// differs from newest.
+func lagPart(version, newest string) (i int) {
+	vParts, nParts := strings.Split(version, "."), strings.Split(newest, ".")
+	for i = 0; i < len(vParts) && i < len(nParts); i++ {
+		if watch.CompareVersions(vParts[i], nParts[i]) != 0 {
+			break
+		}
+	}
+
+	if i >= len(versionParts) {
+		i = len(versionParts) - 1
+	}
+
+	return i
+}
+
+// queryVersions returns the versions of the extension in the store.  The
+// status is used if the store reports it, otherwise the published version is
+// taken from the listing.
+func queryVersions(
+	name string,
+	store string,
+	sources map[string]*watch.Target,
+	listings map[string]*stats.Target,
+) (sv *storeVersions) {
+	key := name + "/" + store
+
+	if t, ok := sources[key]; ok {
+		sv = &storeVersions{Store: store, AppID: t.AppID}
+
+		snapshot, err := t.Source.Snapshot(t.AppID)
+		if err != nil {
+			sv.Error = err.Error()
+
+			return sv
+		}
+
+		sv.State, sv.Version, sv.PendingVersion = string(snapshot.State), snapshot.Version, snapshot.PendingVersion
+
+		return sv
+	}
+
+	if t, ok := listings[key]; ok {
+		sv = &storeVersions{Store: store, AppID: t.AppID}
+
+		sample, err := t.Collector.Collect(t.AppID)
+		if err != nil {
+			sv.Error = err.Error()
+
+			return sv
+		}
+
+		sv.Version = sample.Version
+
+		return sv
+	}
+
+	sv = &storeVersions{Store: store, Error: "versions aren't available"}
+	if store == "edge" {
+		sv.Error = "edge_crx isn't set"
+	}
+
+	return sv
+}
+
+// compareExtension returns the versions of the extension in the stores and
+// marks the stores lagging behind the newest published version.
+func compareExtension(
+	cfg *appConfig,
+	name string,
+	sources map[string]*watch.Target,
+	listings map[string]*stats.Target,
+) (ev *extensionVersions) {
+	ev = &extensionVersions{Extension: name}
+
+	// edge is compared by the listing, so it is found by the CRX ID as well
+	ids := cfg.Extensions[name].storeIDs()
+	for store, id := range cfg.Extensions[name].statsIDs() {
+		if _, ok := ids[store]; !ok {
+			ids[store] = id
+		}
+	}
+
+	stores := make([]string, 0, len(ids))
+	for store := range ids {
+		stores = append(stores, store)
+	}
+	sort.Strings(stores)
+
+	for _, store := range stores {
+		sv := queryVersions(name, store, sources, listings)
+		if sv.AppID == "" {
+			sv.AppID = ids[store]
+		}
+
+		if sv.Version != "" && (ev.Newest == "" || watch.CompareVersions(sv.Version, ev.Newest) > 0) {
+			ev.Newest = sv.Version
+		}
+
+		ev.Stores = append(ev.Stores, sv)
+	}
+
+	for _, sv := range ev.Stores {
+		if sv.Version != "" && watch.CompareVersions(sv.Version, ev.Newest) < 0 {
+			sv.Lag = versionParts[lagPart(sv.Version, ev.Newest)]
+		}
+	}
+
+	return ev
+}
+
+// printVersions prints the version matrix of the extensions.
+func printVersions(output io.Writer, extensions []*extensionVersions) {
+	for i, ev := range extensions {
+		if i > 0 {
+			fmt.Fprintln(output)
+		}
+
+		if ev.Newest != "" {
+			fmt.Fprintf(output, "%s (newest %s)\n", ev.Extension, ev.Newest)
+		} else {
+			fmt.Fprintln(output, ev.Extension)
+		}
+
+		w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "store\tpublished\tpending\tstate\tdrift\n")
+
+		for _, sv := range ev.Stores {
+			drift := ""
+			if sv.Error != "" {
+				drift = "error: " + sv.Error
+			} else if sv.Lag != "" {
+				drift = "behind by " + sv.Lag
+			}
+
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", sv.Store, sv.Version, sv.PendingVersion, sv.State, drift)
+		}
+
+		_ = w.Flush()
+	}
+}
+
+// compareVersions prints the published and pending versions of the configured
+// extensions selected by the command arguments in every store.  It fails if a
+// store lags behind the newest version by the --threshold part or a more
+// significant one, or if the versions in a store can't be retrieved, since the
+// drift can't be checked then.
+func compareVersions(c *cli.Context) (err error) {
+	threshold := -1
+	if c.IsSet("threshold") {
+		for i, part := range versionParts {
+			if part == c.String("threshold") {
+				threshold = i
+			}
+		}
+
+		if threshold < 0 {
+			return fmt.Errorf("unknown threshold %q", c.String("threshold"))
+		}
+	}
+
+	cfg, err := readConfig(c.String("config"))
+	if err != nil {
+		return err
+	}
+
+	names, err := cfg.selectExtensions(c.Args().Slice())
+	if err != nil {
+		return err
+	}
+
+	sources := map[string]*watch.Target{}
+	for _, t := range statusTargets(c, cfg, names) {
+		sources[t.Extension+"/"+t.Store] = t
+	}
+
+	listings := map[string]*stats.Target{}
+	for _, t := range statsTargets(c, cfg, names) {
+		listings[t.Extension+"/"+t.Store] = t
+	}
+
+	extensions := make([]*extensionVersions, 0, len(names))
+	for _, name := range names {
+		extensions = append(extensions, compareExtension(cfg, name, sources, listings))
+	}
+
+	if c.Bool("json") {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		err = encoder.Encode(extensions)
+		if err != nil {
+			return fmt.Errorf("encoding versions: %w", err)
+		}
+	} else {
+		printVersions(os.Stdout, extensions)
+	}
+
+	if threshold < 0 {
+		return nil
+	}
+
+	var lagging, failed []string
+	for _, ev := range extensions {
+		for _, sv := range ev.Stores {
+			if sv.Error != "" {
+				failed = append(failed, fmt.Sprintf("%s (%s)", ev.Extension, sv.Store))
+			} else if sv.Lag != "" && lagPart(sv.Version, ev.Newest) <= threshold {
+				lagging = append(lagging, fmt.Sprintf("%s (%s)", ev.Extension, sv.Store))
+			}
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("can't retrieve versions: %s", strings.Join(failed, ", "))
+	}
+
+	if len(lagging) > 0 {
+		return fmt.Errorf(
+			"stores lag behind the newest version by %s or more: %s",
+			versionParts[threshold],
+			strings.Join(lagging, ", "),
+		)
+	}
+
+	return nil
+}
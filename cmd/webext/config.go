@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// appConfig describes the structure of the config file.
+type appConfig struct {
+	// DefaultProfile is the name of the profile used if no profile is
+	// selected.
+	DefaultProfile string             `json:"default_profile"`
+	Profiles       map[string]profile `json:"profiles"`
+	// Extensions contains the IDs of the extensions in the stores by the
+	// extension name.
+	Extensions map[string]extensionConfig `json:"extensions"`
+	// Notifiers receive the status changes and the release outcomes.
+	Notifiers []notifierConfig `json:"notifiers"`
+}
+
+// extensionConfig contains the IDs of the extension in the stores, the
+// extension isn't published to the store if its ID is empty.
+type extensionConfig struct {
+	Chrome  string `json:"chrome"`
+	Firefox string `json:"firefox"`
+	Edge    string `json:"edge"`
+	// EdgeCRX is the CRX ID of the extension in the Edge Add-ons site, it is
+	// used to retrieve the public statistics and differs from the product ID.
+	EdgeCRX string `json:"edge_crx"`
+	// Alerts are the alert rules evaluated by the watcher, no alerts are sent
+	// if nil.
+	Alerts *alertsConfig `json:"alerts"`
+}
+
+// alertsConfig describes the alert rules of the extension, the zero values
+// disable the rules.
+type alertsConfig struct {
+	// ReviewDays is the number of days the pending version may be in review.
+	ReviewDays int `json:"review_days"`
+	// DriftHours is the number of hours the stores may serve different
+	// versions of the extension.
+	DriftHours int `json:"drift_hours"`
+	// Disabled enables the alert when the extension is disabled or taken
+	// down.
+	Disabled bool `json:"disabled"`
+	// Notifiers are the names of the notifiers receiving the alerts, all
+	// notifiers receive them if empty.
+	Notifiers []string `json:"notifiers"`
+}
+
+// storeIDs returns the IDs of the extension by store name.
+func (e extensionConfig) storeIDs() (ids map[string]string) {
+	ids = map[string]string{}
+	for store, id := range map[string]string{
+		"chrome":  e.Chrome,
+		"firefox": e.Firefox,
+		"edge":    e.Edge,
+	} {
+		if id != "" {
+			ids[store] = id
+		}
+	}
+
+	return ids
+}
+
+// defaultConfigPath returns path to the config file in the user config
+// directory, e.g. ~/.config/extdash/config.json.
+func defaultConfigPath() (configPath string) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "extdash", "config.json")
+}
+
+// readConfig reads the config file.
+func readConfig(configPath string) (cfg *appConfig, err error) {
+	data, err := os.ReadFile(filepath.Clean(configPath))
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+
+	cfg = &appConfig{}
+
+	err = json.Unmarshal(data, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshaling config: %w", err)
+	}
+
+	return cfg, nil
+}
+
+// selectExtensions returns the configured extensions by the names, or all of
+// them if names are empty.  The names are returned sorted.
+func (cfg *appConfig) selectExtensions(names []string) (selected []string, err error) {
+	if len(names) == 0 {
+		for name := range cfg.Extensions {
+			names = append(names, name)
+		}
+	}
+
+	for _, name := range names {
+		if _, ok := cfg.Extensions[name]; !ok {
+			return nil, fmt.Errorf("extension %q is not configured", name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no extensions are configured")
+	}
+
+	sort.Strings(names)
+
+	return names, nil
+}
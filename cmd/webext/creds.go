@@ -0,0 +1,337 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/maximtop/extdash/internal/vault"
+	"github.com/urfave/cli/v2"
+	"golang.org/x/term"
+)
+
+// vaultPassphraseEnv is the environment variable with the vault passphrase,
+// used instead of the prompt in non-interactive environments.
+const vaultPassphraseEnv = "EXTDASH_VAULT_PASSPHRASE"
+
+// credentialNameRe matches the valid names of the credentials in the vault,
+// which are the names of the environment variables.
+var credentialNameRe = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+// defaultVaultPath returns path to the vault in the user config directory,
+// e.g. ~/.config/extdash/vault.json.
+func defaultVaultPath() (vaultPath string) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "extdash", "vault.json")
+}
+
+// readPassphrase returns the vault passphrase from the environment, or prompts
+// for it if stdin is a terminal.
+func readPassphrase(prompt string) (passphrase []byte, err error) {
+	if value, ok := os.LookupEnv(vaultPassphraseEnv); ok {
+		return []byte(value), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return nil, fmt.Errorf("stdin is not a terminal, set %s", vaultPassphraseEnv)
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	passphrase, err = term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("reading passphrase: %w", err)
+	}
+
+	return passphrase, nil
+}
+
+// newPassphrase prompts for the passphrase of the new vault twice.
+func newPassphrase() (passphrase []byte, err error) {
+	passphrase, err = readPassphrase("New vault passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+
+	if len(passphrase) == 0 {
+		return nil, fmt.Errorf("passphrase must not be empty")
+	}
+
+	if _, ok := os.LookupEnv(vaultPassphraseEnv); ok {
+		return passphrase, nil
+	}
+
+	confirmation, err := readPassphrase("Repeat passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+
+	if string(confirmation) != string(passphrase) {
+		return nil, fmt.Errorf("passphrases don't match")
+	}
+
+	return passphrase, nil
+}
+
+// openVault prompts for the passphrase and decrypts the vault.  If the vault
+// doesn't exist and create is true, the passphrase for the new vault is
+// requested.
+func openVault(vaultPath string, create bool) (secrets map[string]string, passphrase []byte, err error) {
+	if vaultPath == "" {
+		return nil, nil, fmt.Errorf("vault path is not set")
+	}
+
+	_, err = os.Stat(vaultPath)
+	if errors.Is(err, os.ErrNotExist) {
+		if !create {
+			return nil, nil, fmt.Errorf("vault %q doesn't exist", vaultPath)
+		}
+
+		passphrase, err = newPassphrase()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return map[string]string{}, passphrase, nil
+	} else if err != nil {
+		return nil, nil, fmt.Errorf("getting vault info: %w", err)
+	}
+
+	passphrase, err = readPassphrase("Vault passphrase: ")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	secrets, err = vault.Load(vaultPath, passphrase)
+	if err != nil {
+		return nil, nil, fmt.Errorf("loading vault: %w", err)
+	}
+
+	return secrets, passphrase, nil
+}
+
+// lazyVault sets the credentials from the vault on demand, so the passphrase is
+// only requested if a store client needs a credential which no other source
+// provided.
+type lazyVault struct {
+	// pinned are the variables which must not be set from the vault, see
+	// pinProfile.
+	pinned map[string]bool
+	path   string
+
+	once sync.Once
+	err  error
+}
+
+// credsVault is the vault of the running command, nil if there is no vault.
+var credsVault *lazyVault
+
+// newLazyVault returns the vault set by the vault flag, or nil if the path is
+// empty or the default vault doesn't exist.
+func newLazyVault(c *cli.Context, pinned map[string]bool) (v *lazyVault, err error) {
+	vaultPath := c.String("vault")
+	if vaultPath == "" {
+		return nil, nil
+	}
+
+	_, err = os.Stat(vaultPath)
+	if errors.Is(err, os.ErrNotExist) {
+		if c.IsSet("vault") {
+			return nil, fmt.Errorf("vault %q doesn't exist", vaultPath)
+		}
+
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("getting vault info: %w", err)
+	}
+
+	return &lazyVault{
+		pinned: pinned,
+		path:   vaultPath,
+	}, nil
+}
+
+// provide decrypts the vault and sets the environment variables, which are not
+// set yet and are not pinned, if any of names is empty.  The vault is decrypted
+// once, v may be nil.
+func (v *lazyVault) provide(names ...string) (err error) {
+	if v == nil {
+		return nil
+	}
+
+	missing := false
+	for _, name := range names {
+		missing = missing || (os.Getenv(name) == "" && !v.pinned[name])
+	}
+
+	if !missing {
+		return nil
+	}
+
+	v.once.Do(func() {
+		var secrets map[string]string
+		secrets, _, v.err = openVault(v.path, false)
+		if v.err == nil {
+			v.err = setUnsetEnv(secrets, v.pinned)
+		}
+	})
+
+	if v.err != nil {
+		return fmt.Errorf("reading credentials from vault: %w", v.err)
+	}
+
+	return nil
+}
+
+// credentialName returns the name of the credential from the first argument.
+func credentialName(c *cli.Context) (name string, err error) {
+	name = c.Args().First()
+	if !credentialNameRe.MatchString(name) {
+		return "", fmt.Errorf("invalid credential name %q, use the environment variable name, e.g. CHROME_CLIENT_SECRET", name)
+	}
+
+	return name, nil
+}
+
+// readCredentialValue returns the value of the credential from the second
+// argument, or reads it from stdin.
+func readCredentialValue(c *cli.Context, name string) (value string, err error) {
+	if c.Args().Len() > 1 {
+		return c.Args().Get(1), nil
+	}
+
+	fd := int(os.Stdin.Fd())
+	if term.IsTerminal(fd) {
+		fmt.Fprintf(os.Stderr, "Value of %s: ", name)
+		data, readErr := term.ReadPassword(fd)
+		fmt.Fprintln(os.Stderr)
+		if readErr != nil {
+			return "", fmt.Errorf("reading value: %w", readErr)
+		}
+
+		return string(data), nil
+	}
+
+	value, err = bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && value == "" {
+		return "", fmt.Errorf("reading value from stdin: %w", err)
+	}
+
+	return strings.TrimRight(value, "\r\n"), nil
+}
+
+// credsSet sets the credential in the vault, the vault is created if it
+// doesn't exist.
+func credsSet(c *cli.Context) (err error) {
+	name, err := credentialName(c)
+	if err != nil {
+		return err
+	}
+
+	value, err := readCredentialValue(c, name)
+	if err != nil {
+		return err
+	}
+
+	return setVaultValue(c.String("vault"), name, value)
+}
+
+// setVaultValue sets the credential in the vault, the vault is created if it
+// doesn't exist.
+func setVaultValue(vaultPath, name, value string) (err error) {
+	secrets, passphrase, err := openVault(vaultPath, true)
+	if err != nil {
+		return err
+	}
+
+	secrets[name] = value
+
+	err = vault.Save(vaultPath, passphrase, secrets)
+	if err != nil {
+		return fmt.Errorf("saving vault: %w", err)
+	}
+
+	return nil
+}
+
+// credsGet prints the value of the credential from the vault.
+func credsGet(c *cli.Context) (err error) {
+	name, err := credentialName(c)
+	if err != nil {
+		return err
+	}
+
+	secrets, _, err := openVault(c.String("vault"), false)
+	if err != nil {
+		return err
+	}
+
+	value, ok := secrets[name]
+	if !ok {
+		return fmt.Errorf("credential %s is not found", name)
+	}
+
+	fmt.Println(value)
+
+	return nil
+}
+
+// credsList prints the names of the credentials in the vault.
+func credsList(c *cli.Context) (err error) {
+	secrets, _, err := openVault(c.String("vault"), false)
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Println(name)
+	}
+
+	return nil
+}
+
+// credsRemove removes the credential from the vault.
+func credsRemove(c *cli.Context) (err error) {
+	name, err := credentialName(c)
+	if err != nil {
+		return err
+	}
+
+	vaultPath := c.String("vault")
+
+	secrets, passphrase, err := openVault(vaultPath, false)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := secrets[name]; !ok {
+		return fmt.Errorf("credential %s is not found", name)
+	}
+
+	delete(secrets, name)
+
+	err = vault.Save(vaultPath, passphrase, secrets)
+	if err != nil {
+		return fmt.Errorf("saving vault: %w", err)
+	}
+
+	return nil
+}
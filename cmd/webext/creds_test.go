@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maximtop/extdash/internal/vault"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLazyVault_provide(t *testing.T) {
+	const passphrase = "passphrase"
+
+	vaultPath := filepath.Join(t.TempDir(), "vault.json")
+	err := vault.Save(vaultPath, []byte(passphrase), map[string]string{
+		"CHROME_CLIENT_ID":     "vault-id",
+		"CHROME_CLIENT_SECRET": "vault-secret",
+		"CHROME_REFRESH_TOKEN": "vault-token",
+	})
+	require.NoError(t, err)
+
+	t.Run("nil", func(t *testing.T) {
+		clearSecretEnv(t)
+
+		var v *lazyVault
+		assert.NoError(t, v.provide("CHROME_CLIENT_ID"))
+	})
+
+	t.Run("provided", func(t *testing.T) {
+		clearSecretEnv(t)
+		t.Setenv("CHROME_CLIENT_ID", "env-id")
+
+		// the passphrase is wrong, so opening the vault would fail
+		t.Setenv(vaultPassphraseEnv, "wrong")
+
+		v := &lazyVault{path: vaultPath}
+		require.NoError(t, v.provide("CHROME_CLIENT_ID"))
+		assert.Equal(t, "env-id", os.Getenv("CHROME_CLIENT_ID"))
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		clearSecretEnv(t)
+		t.Setenv("CHROME_CLIENT_ID", "env-id")
+		t.Setenv(vaultPassphraseEnv, passphrase)
+
+		v := &lazyVault{
+			pinned: map[string]bool{"CHROME_CLIENT_SECRET": true},
+			path:   vaultPath,
+		}
+		require.NoError(t, v.provide("CHROME_CLIENT_ID", "CHROME_REFRESH_TOKEN"))
+		assert.Equal(t, "env-id", os.Getenv("CHROME_CLIENT_ID"))
+		assert.Equal(t, "vault-token", os.Getenv("CHROME_REFRESH_TOKEN"))
+
+		_, ok := os.LookupEnv("CHROME_CLIENT_SECRET")
+		assert.False(t, ok)
+	})
+
+	t.Run("wrong passphrase", func(t *testing.T) {
+		clearSecretEnv(t)
+		t.Setenv(vaultPassphraseEnv, "wrong")
+
+		v := &lazyVault{path: vaultPath}
+		assert.Error(t, v.provide("CHROME_CLIENT_ID"))
+		assert.Error(t, v.provide("CHROME_CLIENT_SECRET"))
+	})
+}
+
+func TestSetVaultValue(t *testing.T) {
+	const passphrase = "passphrase"
+
+	t.Setenv(vaultPassphraseEnv, passphrase)
+
+	vaultPath := filepath.Join(t.TempDir(), "vault.json")
+
+	err := setVaultValue(vaultPath, "CHROME_CLIENT_ID", "id")
+	require.NoError(t, err)
+
+	err = setVaultValue(vaultPath, "CHROME_REFRESH_TOKEN", "token")
+	require.NoError(t, err)
+
+	secrets, err := vault.Load(vaultPath, []byte(passphrase))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]string{
+		"CHROME_CLIENT_ID":     "id",
+		"CHROME_REFRESH_TOKEN": "token",
+	}, secrets)
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/log"
+	"github.com/maximtop/extdash/internal/fileutil"
+	"github.com/maximtop/extdash/internal/history"
+	"github.com/maximtop/extdash/internal/notify"
+	"github.com/maximtop/extdash/internal/stats"
+	"github.com/urfave/cli/v2"
+)
+
+// releaseResultKey is the key of the store response in the app metadata.
+const releaseResultKey = "release_result"
+
+// defaultHistoryPath returns path to the history database in the user config
+// directory, e.g. ~/.config/extdash/history.db.
+func defaultHistoryPath() (historyPath string) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "extdash", "history.db")
+}
+
+// printResult prints the store response to the release command and keeps it
+// to be recorded in the history.
+func printResult(c *cli.Context, result any) {
+	fmt.Println(result)
+
+	if c.App.Metadata == nil {
+		c.App.Metadata = map[string]any{}
+	}
+
+	c.App.Metadata[releaseResultKey] = result
+}
+
+// geckoSettings contains the Firefox-specific settings of the manifest.
+type geckoSettings struct {
+	Gecko struct {
+		ID string `json:"id"`
+	} `json:"gecko"`
+}
+
+// extensionManifest contains the fields of the extension manifest used in the
+// release events.
+type extensionManifest struct {
+	Version                 string        `json:"version"`
+	BrowserSpecificSettings geckoSettings `json:"browser_specific_settings"`
+	// Applications is the legacy name of BrowserSpecificSettings.
+	Applications geckoSettings `json:"applications"`
+}
+
+// geckoID returns the ID of the Firefox add-on, or an empty string if the
+// manifest doesn't set it.
+func (m *extensionManifest) geckoID() (id string) {
+	if id = m.BrowserSpecificSettings.Gecko.ID; id != "" {
+		return id
+	}
+
+	return m.Applications.Gecko.ID
+}
+
+// readManifest returns the manifest of the extension archive.  The fields are
+// empty if it can't be read.
+func readManifest(zipPath string) (manifest *extensionManifest) {
+	manifest = &extensionManifest{}
+	if zipPath == "" {
+		return manifest
+	}
+
+	data, err := fileutil.ReadFileFromZip(zipPath, "manifest.json")
+	if err != nil {
+		log.Debug("reading manifest: %s", err)
+
+		return manifest
+	}
+
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		log.Debug("unmarshaling manifest: %s", err)
+
+		return &extensionManifest{}
+	}
+
+	return manifest
+}
+
+// releaseVersion returns the version of the release: the version from the
+// manifest of the archive or, for the releases without the archive, e.g.
+// publish in edge, the version of the last successful release of the app with
+// the archive recorded to the history database at the path.
+func releaseVersion(historyPath string, manifest *extensionManifest, store, appID string) (version string) {
+	if manifest.Version != "" || historyPath == "" || appID == "" {
+		return manifest.Version
+	}
+
+	version, err := lastReleaseVersion(historyPath, store, appID)
+	if err != nil {
+		log.Debug("reading release version from history: %s", err)
+	}
+
+	return version
+}
+
+// lastReleaseVersion returns the version of the last successful release of the
+// app in the store, which version is known, or an empty string.
+func lastReleaseVersion(historyPath, store, appID string) (version string, err error) {
+	db, err := history.Open(historyPath)
+	if err != nil {
+		return "", err
+	}
+	defer func() { err = errors.WithDeferred(err, db.Close()) }()
+
+	records, err := db.Find(&history.Filter{
+		Kind:  history.KindRelease,
+		Store: store,
+		AppID: appID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("reading history: %w", err)
+	}
+
+	for i := len(records) - 1; i >= 0; i-- {
+		r := records[i]
+		if r.State == history.ReleaseSucceeded && r.Version != "" {
+			return r.Version, nil
+		}
+	}
+
+	return "", nil
+}
+
+// recordRelease saves the release event and the store response to the history
+// database.  The errors are logged, since they shouldn't affect the release.
+func recordRelease(c *cli.Context, event *notify.Event) {
+	saveRelease(c.String("history"), event, c.App.Metadata[releaseResultKey])
+}
+
+// saveRelease saves the release event and the store response, if not nil, to
+// the history database at the path.  It does nothing if the path is empty.
+// The errors are logged.
+func saveRelease(historyPath string, event *notify.Event, result any) {
+	if historyPath == "" {
+		return
+	}
+
+	record := history.FromEvent(event)
+
+	if result != nil {
+		response, err := json.Marshal(result)
+		if err != nil {
+			log.Debug("marshaling store response: %s", err)
+		} else {
+			record.Response = response
+		}
+	}
+
+	err := history.Add(historyPath, record)
+	if err != nil {
+		log.Error("recording release to history: %s", err)
+	}
+}
+
+// recordSamples saves the statistics samples to the history database.
+func recordSamples(c *cli.Context, samples []*stats.Sample) (err error) {
+	historyPath := c.String("history")
+	if historyPath == "" {
+		return nil
+	}
+
+	records := make([]*history.Record, 0, len(samples))
+	for _, sample := range samples {
+		records = append(records, history.FromSample(sample))
+	}
+
+	return history.Add(historyPath, records...)
+}
+
+// showHistory prints the history records selected by the flags.
+func showHistory(c *cli.Context) (err error) {
+	historyPath := c.String("history")
+	if historyPath == "" {
+		return fmt.Errorf("path to the history database is empty")
+	}
+
+	filter := &history.Filter{
+		Kind:      history.Kind(c.String("kind")),
+		Extension: c.String("extension"),
+		Store:     c.String("store"),
+		AppID:     c.String("app"),
+		Version:   c.String("version"),
+	}
+
+	switch filter.Kind {
+	case "", history.KindRelease, history.KindStatus, history.KindStats, history.KindAlert:
+		// go on
+	default:
+		return fmt.Errorf("unknown kind %q", filter.Kind)
+	}
+
+	if c.IsSet("since") {
+		filter.Since = time.Now().Add(-c.Duration("since"))
+	}
+
+	db, err := history.Open(historyPath)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.WithDeferred(err, db.Close()) }()
+
+	records, err := db.Find(filter)
+	if err != nil {
+		return fmt.Errorf("reading history: %w", err)
+	}
+
+	if c.Bool("json") {
+		if records == nil {
+			records = []*history.Record{}
+		}
+
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(records)
+	}
+
+	printRecords(os.Stdout, records)
+
+	return nil
+}
+
+// printRecords prints the history records as the table.
+func printRecords(output io.Writer, records []*history.Record) {
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "time\tkind\textension\tstore\tapp\tversion\tdetails\n")
+	for _, r := range records {
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Time.Local().Format(time.RFC3339),
+			r.Kind,
+			r.Extension,
+			r.Store,
+			r.AppID,
+			r.Version,
+			recordDetails(r),
+		)
+	}
+
+	_ = w.Flush()
+}
+
+// recordDetails returns the short description of the record depending on its
+// kind.
+func recordDetails(r *history.Record) (details string) {
+	switch r.Kind {
+	case history.KindRelease:
+		details = r.Operation + " " + r.State
+		if r.Message != "" {
+			details += ": " + r.Message
+		}
+	case history.KindStatus:
+		details = r.State
+		if r.PreviousState != "" {
+			details = r.PreviousState + " -> " + r.State
+		}
+
+		if r.PendingVersion != "" {
+			details += ", pending " + r.PendingVersion
+		}
+	case history.KindAlert:
+		details = r.Rule + " alert"
+		if r.Message != "" {
+			details += ": " + r.Message
+		}
+	case history.KindStats:
+		if r.Stats != nil {
+			details = fmt.Sprintf("users %d, rating %.2f (%d)", r.Stats.Users, r.Stats.Rating, r.Stats.RatingCount)
+		}
+	}
+
+	return details
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maximtop/extdash/internal/history"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReleaseVersion(t *testing.T) {
+	historyPath := filepath.Join(t.TempDir(), "history.db")
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	err := history.Add(historyPath, &history.Record{
+		Time:      now,
+		Kind:      history.KindRelease,
+		Store:     "edge",
+		AppID:     "edge-app",
+		Operation: "update",
+		Version:   "4.1.1",
+		State:     history.ReleaseSucceeded,
+	}, &history.Record{
+		Time:      now.Add(time.Hour),
+		Kind:      history.KindRelease,
+		Store:     "edge",
+		AppID:     "edge-app",
+		Operation: "update",
+		Version:   "4.1.2",
+		State:     history.ReleaseSucceeded,
+	}, &history.Record{
+		Time:      now.Add(2 * time.Hour),
+		Kind:      history.KindRelease,
+		Store:     "edge",
+		AppID:     "edge-app",
+		Operation: "update",
+		Version:   "4.1.3",
+		State:     history.ReleaseFailed,
+	}, &history.Record{
+		Time:      now.Add(3 * time.Hour),
+		Kind:      history.KindRelease,
+		Store:     "chrome",
+		AppID:     "chrome-app",
+		Operation: "update",
+		Version:   "4.1.4",
+		State:     history.ReleaseSucceeded,
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name     string
+		manifest *extensionManifest
+		store    string
+		appID    string
+		want     string
+	}{{
+		name:     "manifest",
+		manifest: &extensionManifest{Version: "5.0.0"},
+		store:    "edge",
+		appID:    "edge-app",
+		want:     "5.0.0",
+	}, {
+		name:     "last upload",
+		manifest: &extensionManifest{},
+		store:    "edge",
+		appID:    "edge-app",
+		want:     "4.1.2",
+	}, {
+		name:     "other app",
+		manifest: &extensionManifest{},
+		store:    "edge",
+		appID:    "other-app",
+		want:     "",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			version := releaseVersion(historyPath, tc.manifest, tc.store, tc.appID)
+			assert.Equal(t, tc.want, version)
+		})
+	}
+}
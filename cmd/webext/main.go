@@ -2,70 +2,226 @@ package main
 
 import (
 	"fmt"
+	"io"
 	"log"
 	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/AdguardTeam/golibs/errors"
 	"github.com/caarlos0/env/v6"
 	"github.com/joho/godotenv"
 	"github.com/maximtop/extdash/internal/chrome"
+	"github.com/maximtop/extdash/internal/dashboard"
 	"github.com/maximtop/extdash/internal/edge"
+	"github.com/maximtop/extdash/internal/fileutil"
 	"github.com/maximtop/extdash/internal/firefox"
+	"github.com/maximtop/extdash/internal/watch"
 	"github.com/urfave/cli/v2"
 )
 
-func getChromeStore() (*chrome.Store, error) {
+// requireEnv returns an error listing the environment variables which values
+// are empty.
+func requireEnv(vars map[string]string) (err error) {
+	var missing []string
+	for name, value := range vars {
+		if value == "" {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	sort.Strings(missing)
+
+	return fmt.Errorf("required environment variables are not set: %s", strings.Join(missing, ", "))
+}
+
+// parseBaseURL parses the base URL of the store API from the environment
+// variable.  It returns defaultURL if raw is empty.
+func parseBaseURL(name, raw string, defaultURL *url.URL) (u *url.URL, err error) {
+	if raw == "" {
+		return defaultURL, nil
+	}
+
+	u, err = url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", name, err)
+	}
+
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%s must be an absolute URL, got %q", name, raw)
+	}
+
+	return u, nil
+}
+
+// newChromeServiceAccountClient creates the chrome client authorized with the
+// service account JSON key from the file.
+func newChromeServiceAccountClient(keyFile string) (client *chrome.ServiceAccountClient, err error) {
+	data, err := os.ReadFile(filepath.Clean(keyFile))
+	if err != nil {
+		return nil, fmt.Errorf("reading service account key: %w", err)
+	}
+
+	key, err := chrome.ParseServiceAccountKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing service account key: %w", err)
+	}
+
+	return &chrome.ServiceAccountClient{Key: key}, nil
+}
+
+// Chrome Web Store API versions selected by CHROME_API_VERSION.
+const (
+	chromeAPIV1 = "v1"
+	chromeAPIV2 = "v2"
+)
+
+// getChromeStore creates the chrome store for the API version selected by
+// CHROME_API_VERSION, only one of the returned stores is not nil.
+func getChromeStore() (store *chrome.Store, storeV2 *chrome.StoreV2, err error) {
 	type config struct {
-		ClientID     string `env:"CHROME_CLIENT_ID,notEmpty"`
-		ClientSecret string `env:"CHROME_CLIENT_SECRET,notEmpty"`
-		RefreshToken string `env:"CHROME_REFRESH_TOKEN,notEmpty"`
+		ClientID              string `env:"CHROME_CLIENT_ID"`
+		ClientSecret          string `env:"CHROME_CLIENT_SECRET"`
+		RefreshToken          string `env:"CHROME_REFRESH_TOKEN"`
+		ServiceAccountKeyFile string `env:"CHROME_SERVICE_ACCOUNT_KEY_FILE"`
+		APIVersion            string `env:"CHROME_API_VERSION" envDefault:"v1"`
+		PublisherID           string `env:"CHROME_PUBLISHER_ID"`
+		APIURL                string `env:"CHROME_API_URL"`
+		TokenURL              string `env:"CHROME_TOKEN_URL"`
+	}
+
+	if os.Getenv("CHROME_SERVICE_ACCOUNT_KEY_FILE") == "" {
+		err = credsVault.provide("CHROME_CLIENT_ID", "CHROME_CLIENT_SECRET", "CHROME_REFRESH_TOKEN")
+		if err != nil {
+			return nil, nil, err
+		}
 	}
 
 	cfg := config{}
-	if err := env.Parse(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to parse environment variables: %w", err)
+	if err = env.Parse(&cfg); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse environment variables: %w", err)
 	}
 
-	client := chrome.Client{
-		URL:          "https://accounts.google.com/o/oauth2/token",
-		ClientID:     cfg.ClientID,
-		ClientSecret: cfg.ClientSecret,
-		RefreshToken: cfg.RefreshToken,
+	if cfg.TokenURL == "" {
+		cfg.TokenURL = chromeTokenURL
 	}
 
-	store := chrome.Store{
-		Client: &client,
-		URL: &url.URL{
+	var client chrome.Authorizer
+	if cfg.ServiceAccountKeyFile != "" {
+		client, err = newChromeServiceAccountClient(cfg.ServiceAccountKeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("initializing service account client: %w", err)
+		}
+	} else {
+		err = requireEnv(map[string]string{
+			"CHROME_CLIENT_ID":     cfg.ClientID,
+			"CHROME_CLIENT_SECRET": cfg.ClientSecret,
+			"CHROME_REFRESH_TOKEN": cfg.RefreshToken,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		client = &chrome.Client{
+			URL:          cfg.TokenURL,
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RefreshToken: cfg.RefreshToken,
+		}
+	}
+
+	switch cfg.APIVersion {
+	case chromeAPIV1:
+		apiURL, err := parseBaseURL("CHROME_API_URL", cfg.APIURL, &url.URL{
 			Scheme: "https",
 			Host:   "www.googleapis.com",
-		},
-	}
+		})
+		if err != nil {
+			return nil, nil, err
+		}
 
-	return &store, nil
+		return &chrome.Store{
+			Client: client,
+			URL:    apiURL,
+		}, nil, nil
+	case chromeAPIV2:
+		if cfg.PublisherID == "" {
+			return nil, nil, fmt.Errorf("CHROME_PUBLISHER_ID is required for the API %s", chromeAPIV2)
+		}
+
+		apiURL, err := parseBaseURL("CHROME_API_URL", cfg.APIURL, &url.URL{
+			Scheme: "https",
+			Host:   "chromewebstore.googleapis.com",
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return nil, &chrome.StoreV2{
+			Client:      client,
+			URL:         apiURL,
+			PublisherID: cfg.PublisherID,
+		}, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown CHROME_API_VERSION %q", cfg.APIVersion)
+	}
 }
 
-func getFirefoxStore() (*firefox.Store, error) {
+// getFirefoxStore creates the firefox store, the polling options are taken
+// from the command flags if they are set.  The AMO environment is selected by
+// the env flag or FIREFOX_ENV, FIREFOX_API_URL overrides it.
+func getFirefoxStore(c *cli.Context) (*firefox.Store, error) {
 	type config struct {
 		ClientID     string `env:"FIREFOX_CLIENT_ID,notEmpty"`
 		ClientSecret string `env:"FIREFOX_CLIENT_SECRET,notEmpty"`
+		Env          string `env:"FIREFOX_ENV"`
+		APIURL       string `env:"FIREFOX_API_URL"`
+	}
+
+	err := credsVault.provide("FIREFOX_CLIENT_ID", "FIREFOX_CLIENT_SECRET")
+	if err != nil {
+		return nil, err
 	}
 
 	cfg := config{}
-	if err := env.Parse(&cfg); err != nil {
+	if err = env.Parse(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse environment variables: %w", err)
 	}
 
+	if c.IsSet("env") {
+		cfg.Env = c.String("env")
+	}
+
+	envURL, err := firefox.EnvironmentURL(firefox.Environment(cfg.Env))
+	if err != nil {
+		return nil, fmt.Errorf("selecting environment: %w", err)
+	}
+
+	apiURL, err := parseBaseURL("FIREFOX_API_URL", cfg.APIURL, envURL)
+	if err != nil {
+		return nil, err
+	}
+
 	client := firefox.NewClient(firefox.ClientConfig{
 		ClientID:     cfg.ClientID,
 		ClientSecret: cfg.ClientSecret,
 	})
 
 	store := firefox.Store{
-		Client: &client,
-		URL: &url.URL{
-			Scheme: "https",
-			Host:   "addons.mozilla.org",
-		},
+		Client:             &client,
+		URL:                apiURL,
+		PollInterval:       c.Duration("poll-interval"),
+		WaitTimeout:        c.Duration("wait-timeout"),
+		ReviewPollInterval: c.Duration("review-poll-interval"),
+		ReviewWaitTimeout:  c.Duration("review-timeout"),
 	}
 
 	return &store, nil
@@ -73,37 +229,225 @@ func getFirefoxStore() (*firefox.Store, error) {
 
 func getEdgeStore() (*edge.Store, error) {
 	type config struct {
-		ClientID     string `env:"EDGE_CLIENT_ID,notEmpty"`
-		ClientSecret string `env:"EDGE_CLIENT_SECRET,notEmpty"`
-		AccessToken  string `env:"EDGE_ACCESS_TOKEN,notEmpty"`
+		ClientID       string `env:"EDGE_CLIENT_ID,notEmpty"`
+		ClientSecret   string `env:"EDGE_CLIENT_SECRET,notEmpty"`
+		AccessTokenURL string `env:"EDGE_ACCESS_TOKEN_URL"`
+		// Deprecated: use EDGE_ACCESS_TOKEN_URL.
+		LegacyAccessTokenURL string `env:"EDGE_ACCESS_TOKEN"`
+		APIURL               string `env:"EDGE_API_URL"`
+	}
+
+	names := []string{"EDGE_CLIENT_ID", "EDGE_CLIENT_SECRET"}
+	if os.Getenv("EDGE_ACCESS_TOKEN") == "" {
+		names = append(names, "EDGE_ACCESS_TOKEN_URL")
+	}
+
+	err := credsVault.provide(names...)
+	if err != nil {
+		return nil, err
 	}
 
 	cfg := config{}
-	if err := env.Parse(&cfg); err != nil {
+	if err = env.Parse(&cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse environment variables: %w", err)
 	}
 
+	if cfg.AccessTokenURL == "" {
+		cfg.AccessTokenURL = cfg.LegacyAccessTokenURL
+	}
+
+	if cfg.AccessTokenURL == "" {
+		return nil, fmt.Errorf("required environment variables are not set: EDGE_ACCESS_TOKEN_URL")
+	}
+
 	client, err := edge.NewClient(
 		cfg.ClientID,
 		cfg.ClientSecret,
-		cfg.AccessToken,
+		cfg.AccessTokenURL,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize Edge Store Client: %w", err)
 	}
 
+	apiURL, err := parseBaseURL("EDGE_API_URL", cfg.APIURL, &url.URL{
+		Scheme: "https",
+		Host:   "api.addons.microsoftedge.microsoft.com",
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	store := edge.Store{
 		Client: &client,
-		URL: &url.URL{
-			Scheme: "https",
-			Host:   "api.addons.microsoftedge.microsoft.com",
-		},
+		URL:    apiURL,
 	}
 
 	return &store, nil
 }
 
+// firefoxSource returns path to the source code archive for the firefox store.
+// The archive is built from the git working tree if the source-git flag is set,
+// in this case cleanup removes it.  sourcepath is empty if no source is
+// provided.
+func firefoxSource(c *cli.Context) (sourcepath string, cleanup func(), err error) {
+	cleanup = func() {}
+
+	sourcepath = c.String("source")
+	gitDir := c.String("source-git")
+
+	if gitDir == "" {
+		if c.String("build-instructions") != "" {
+			return "", cleanup, fmt.Errorf(
+				"flag --build-instructions requires --source-git, add the file to the --source archive instead",
+			)
+		}
+
+		return sourcepath, cleanup, nil
+	}
+
+	if sourcepath != "" {
+		return "", cleanup, fmt.Errorf("flags --source and --source-git can't be used together")
+	}
+
+	archive, err := os.CreateTemp("", "source-*.zip")
+	if err != nil {
+		return "", cleanup, fmt.Errorf("creating temporary file: %w", err)
+	}
+
+	sourcepath = archive.Name()
+	cleanup = func() {
+		removeErr := os.Remove(sourcepath)
+		if removeErr != nil {
+			log.Printf("failed to remove %q: %s", sourcepath, removeErr)
+		}
+	}
+
+	err = archive.Close()
+	if err != nil {
+		cleanup()
+
+		return "", func() {}, fmt.Errorf("closing temporary file: %w", err)
+	}
+
+	var extraFiles []string
+	if instructions := c.String("build-instructions"); instructions != "" {
+		extraFiles = append(extraFiles, instructions)
+	}
+
+	err = fileutil.ZipGitWorkTree(gitDir, sourcepath, extraFiles...)
+	if err != nil {
+		cleanup()
+
+		return "", func() {}, fmt.Errorf("archiving %q: %w", gitDir, err)
+	}
+
+	return sourcepath, cleanup, nil
+}
+
+// printChromeStatus prints the status of the chrome extension.
+func printChromeStatus(output io.Writer, status *chrome.StatusResponse) {
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "id:\t%s\n", status.ID)
+	fmt.Fprintf(w, "version:\t%s\n", status.CrxVersion)
+	fmt.Fprintf(w, "upload state:\t%s\n", status.UploadState)
+
+	for _, itemErr := range status.ItemError {
+		fmt.Fprintf(w, "error:\t%s\n", itemErr)
+	}
+
+	_ = w.Flush()
+}
+
+// printChromeVersions prints the draft and the published versions of the
+// chrome extension side by side.
+func printChromeVersions(output io.Writer, versions *chrome.Versions) {
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "\tdraft\tpublished\n")
+	fmt.Fprintf(w, "version:\t%s\t%s\n", versions.Draft.CrxVersion, versions.Published.CrxVersion)
+	fmt.Fprintf(w, "upload state:\t%s\t%s\n", versions.Draft.UploadState, versions.Published.UploadState)
+
+	_ = w.Flush()
+
+	if versions.Live() {
+		fmt.Fprintln(output, "the last uploaded version is published")
+	} else {
+		fmt.Fprintln(output, "the last uploaded version is not published yet")
+	}
+}
+
+// printChromeStatusV2 prints the published and the submitted revisions of the
+// chrome extension side by side.
+func printChromeStatusV2(output io.Writer, status *chrome.StatusResponseV2) {
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+
+	published, submitted := status.PublishedItemRevisionStatus, status.SubmittedItemRevisionStatus
+
+	var publishedState, submittedState chrome.ItemState
+	if published != nil {
+		publishedState = published.State
+	}
+
+	if submitted != nil {
+		submittedState = submitted.State
+	}
+
+	fmt.Fprintf(w, "\tpublished\tsubmitted\n")
+	fmt.Fprintf(w, "version:\t%s\t%s\n", published.CrxVersion(), submitted.CrxVersion())
+	fmt.Fprintf(w, "state:\t%s\t%s\n", publishedState, submittedState)
+
+	_ = w.Flush()
+
+	fmt.Fprintf(output, "last upload state: %s\n", status.LastAsyncUploadState)
+
+	if status.TakenDown {
+		fmt.Fprintln(output, "the extension is taken down")
+	} else if status.Warned {
+		fmt.Fprintln(output, "the extension is warned")
+	}
+}
+
+// printValidationReport prints the validation report to stderr if err is
+// caused by an invalid firefox extension.
+func printValidationReport(err error) {
+	var validationErr *firefox.ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Report == nil {
+		return
+	}
+
+	report := validationErr.Report
+
+	fmt.Fprintf(
+		os.Stderr,
+		"validation report: %d errors, %d warnings, %d notices\n",
+		report.Errors,
+		report.Warnings,
+		report.Notices,
+	)
+
+	for _, msg := range report.Messages {
+		location := msg.Location()
+		if location != "" {
+			location += ": "
+		}
+
+		fmt.Fprintf(os.Stderr, "  %s: %s%s\n", msg.Type, location, msg.Message)
+
+		if msg.Description != "" {
+			description := strings.ReplaceAll(string(msg.Description), "\n", "\n    ")
+			fmt.Fprintf(os.Stderr, "    %s\n", description)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "full report: %s\n", validationErr.URL)
+}
+
 func main() {
+	// the process environment takes precedence over the explicit profile, so
+	// it's recorded before the .env file is loaded
+	processEnv := envNames()
+
 	// we don't care if method fails on reading .env file, we will try to read config from environment
 	// variables later
 	_ = godotenv.Load()
@@ -111,13 +455,301 @@ func main() {
 	app := &cli.App{
 		Name:  "extdash",
 		Usage: "Cli application for managing extensions in the store",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:    "profile",
+				Aliases: []string{"p"},
+				Usage:   "name of the credentials profile from the config file",
+				EnvVars: []string{"EXTDASH_PROFILE"},
+			},
+			&cli.StringFlag{
+				Name:    "config",
+				Usage:   "path to the config file with the credentials profiles",
+				EnvVars: []string{"EXTDASH_CONFIG"},
+				Value:   defaultConfigPath(),
+			},
+			&cli.StringFlag{
+				Name:  "env",
+				Usage: "AMO environment: prod, stage or dev, overrides FIREFOX_ENV",
+			},
+			&cli.BoolFlag{
+				Name:  "secrets-stdin",
+				Usage: "read credentials in the .env format from stdin",
+			},
+			&cli.StringFlag{
+				Name:    "credential-helper",
+				Usage:   "command printing the credentials as a JSON object",
+				EnvVars: []string{"EXTDASH_CREDENTIAL_HELPER"},
+			},
+			&cli.StringFlag{
+				Name:    "vault",
+				Usage:   "path to the encrypted credentials file",
+				EnvVars: []string{"EXTDASH_VAULT"},
+				Value:   defaultVaultPath(),
+			},
+			&cli.StringFlag{
+				Name:    "history",
+				Usage:   "path to the history database, the history isn't recorded if empty",
+				EnvVars: []string{"EXTDASH_HISTORY"},
+				Value:   defaultHistoryPath(),
+			},
+		},
+		Before: func(c *cli.Context) (err error) {
+			return loadCredentials(c, processEnv)
+		},
 	}
 
 	appFlag := &cli.StringFlag{Name: "app", Aliases: []string{"a"}, Required: true}
 	fileFlag := &cli.StringFlag{Name: "file", Aliases: []string{"f"}, Required: true}
-	sourceFlag := &cli.StringFlag{Name: "source", Aliases: []string{"s"}, Required: true}
+	sourceFlag := &cli.StringFlag{Name: "source", Aliases: []string{"s"}, Usage: "path to the source code archive"}
+	sourceGitFlag := &cli.StringFlag{
+		Name:  "source-git",
+		Usage: "path to the git working tree to build the source code archive from",
+	}
+	buildInstructionsFlag := &cli.StringFlag{
+		Name:  "build-instructions",
+		Usage: "path to the file with build instructions added to the source code archive built from git",
+	}
+	pollIntervalFlag := &cli.DurationFlag{
+		Name:  "poll-interval",
+		Usage: "initial interval between status checks, doubled after every check",
+		Value: firefox.DefaultPollInterval,
+	}
+	waitTimeoutFlag := &cli.DurationFlag{
+		Name:  "wait-timeout",
+		Usage: "maximum time to wait for the store to process the extension",
+		Value: firefox.DefaultWaitTimeout,
+	}
 
 	app.Commands = []*cli.Command{
+		{
+			Name:  "creds",
+			Usage: "manages credentials in the encrypted vault",
+			Subcommands: []*cli.Command{
+				{
+					Name:      "set",
+					Usage:     "sets the credential, the value is read from stdin if not passed",
+					ArgsUsage: "NAME [VALUE]",
+					Action:    credsSet,
+				},
+				{
+					Name:      "get",
+					Usage:     "prints the value of the credential",
+					ArgsUsage: "NAME",
+					Action:    credsGet,
+				},
+				{
+					Name:   "list",
+					Usage:  "lists names of the credentials",
+					Action: credsList,
+				},
+				{
+					Name:      "rm",
+					Usage:     "removes the credential",
+					ArgsUsage: "NAME",
+					Action:    credsRemove,
+				},
+			},
+		},
+		{
+			Name:      "watch",
+			Usage:     "polls status of the configured extensions and notifies about the changes",
+			ArgsUsage: "[extension...]",
+			Flags: []cli.Flag{
+				&cli.DurationFlag{
+					Name:  "interval",
+					Usage: "interval between the status checks",
+					Value: watch.DefaultInterval,
+				},
+				&cli.BoolFlag{
+					Name:  "once",
+					Usage: "check the status once and exit",
+				},
+				&cli.StringFlag{
+					Name:  "state",
+					Usage: "path to the file with the last known status",
+					Value: defaultStatePath(),
+				},
+			},
+			Action: watchExtensions,
+		},
+		{
+			Name:      "compare",
+			Usage:     "compares the published and pending versions of the configured extensions across the stores",
+			ArgsUsage: "[extension...]",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "threshold",
+					Usage: "fail if a store lags behind the newest version by the part or more: major, minor, patch or build",
+				},
+				&cli.BoolFlag{
+					Name:  "json",
+					Usage: "print the versions as JSON",
+				},
+			},
+			Action: compareVersions,
+		},
+		{
+			Name:  "stats",
+			Usage: "collects and exports statistics of the configured extensions",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:  "stats",
+					Usage: "path to the file with the collected statistics",
+					Value: defaultStatsPath(),
+				},
+			},
+			Subcommands: []*cli.Command{
+				{
+					Name:      "collect",
+					Usage:     "collects users and ratings from the stores and saves them",
+					ArgsUsage: "[extension...]",
+					Action:    collectStats,
+				},
+				{
+					Name:  "export",
+					Usage: "exports the collected statistics",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:  "format",
+							Usage: "output format, csv or json",
+							Value: formatCSV,
+						},
+						&cli.StringFlag{
+							Name:  "extension",
+							Usage: "export only statistics of the extension",
+						},
+						&cli.StringFlag{
+							Name:  "store",
+							Usage: "export only statistics from the store",
+						},
+						&cli.DurationFlag{
+							Name:  "since",
+							Usage: "export only statistics collected within the duration, e.g. 720h",
+						},
+						&cli.StringFlag{
+							Name:    "output",
+							Aliases: []string{"o"},
+							Usage:   "path to the output file, stdout if not set",
+						},
+					},
+					Action: exportStats,
+				},
+			},
+		},
+		{
+			Name:      "serve",
+			Usage:     "runs the web dashboard of the configured extensions",
+			ArgsUsage: "[extension...]",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "addr",
+					Usage:   "address to listen on",
+					EnvVars: []string{"EXTDASH_ADDR"},
+					Value:   defaultServeAddr,
+				},
+				&cli.DurationFlag{
+					Name:  "interval",
+					Usage: "interval between the status refreshes",
+					Value: watch.DefaultInterval,
+				},
+				&cli.DurationFlag{
+					Name:  "stats-interval",
+					Usage: "interval between the stats collections",
+					Value: dashboard.DefaultStatsInterval,
+				},
+				&cli.StringFlag{
+					Name:  "state",
+					Usage: "path to the file with the last known status",
+					Value: defaultStatePath(),
+				},
+				&cli.StringFlag{
+					Name:    "api-token",
+					Usage:   "token of the REST API, the API is disabled if empty",
+					EnvVars: []string{"EXTDASH_API_TOKEN"},
+				},
+			},
+			Action: serve,
+		},
+		{
+			Name:  "history",
+			Usage: "shows the recorded releases, status changes and stats",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:    "app",
+					Aliases: []string{"a"},
+					Usage:   "show only records of the extension with the ID",
+				},
+				&cli.StringFlag{
+					Name:  "store",
+					Usage: "show only records of the store",
+				},
+				&cli.StringFlag{
+					Name:  "extension",
+					Usage: "show only records of the configured extension",
+				},
+				&cli.StringFlag{
+					Name:  "kind",
+					Usage: "show only records of the kind: release, status, stats or alert",
+				},
+				&cli.StringFlag{
+					Name:  "version",
+					Usage: "show only records of the version",
+				},
+				&cli.DurationFlag{
+					Name:  "since",
+					Usage: "show only records within the duration, e.g. 720h",
+				},
+				&cli.BoolFlag{
+					Name:  "json",
+					Usage: "print the records as JSON",
+				},
+			},
+			Action: showHistory,
+		},
+		{
+			Name:   "profiles",
+			Usage:  "lists the credentials profiles from the config file",
+			Action: listProfiles,
+		},
+		{
+			Name:  "auth",
+			Usage: "obtains credentials for the stores",
+			Subcommands: []*cli.Command{
+				{
+					Name:  "chrome",
+					Usage: "obtains chrome refresh token and saves it to the .env file or to the vault",
+					Flags: []cli.Flag{
+						&cli.IntFlag{
+							Name:  "port",
+							Usage: "port of the local redirect server, random if not set",
+						},
+						&cli.DurationFlag{
+							Name:  "timeout",
+							Usage: "maximum time to wait for the access to be granted",
+							Value: 5 * time.Minute,
+						},
+						&cli.StringFlag{
+							Name:  "env-file",
+							Usage: "path to the .env file to save the refresh token to",
+							Value: ".env",
+						},
+						&cli.StringFlag{
+							Name:  "save-to",
+							Usage: "where to save the refresh token: env for the .env file or vault",
+							Value: saveToEnv,
+						},
+					},
+					Action: authChrome,
+				},
+				{
+					Name:      "check",
+					Usage:     "checks credentials of the stores: chrome, firefox, edge, all if not set",
+					ArgsUsage: "[store...]",
+					Action:    authCheck,
+				},
+			},
+		},
 		{
 			Name:  "status",
 			Usage: "returns extension info",
@@ -126,7 +758,7 @@ func main() {
 					Name:  "firefox",
 					Usage: "Firefox Store",
 					Action: func(c *cli.Context) error {
-						store, err := getFirefoxStore()
+						store, err := getFirefoxStore(c)
 						if err != nil {
 							return fmt.Errorf("initializing firefox store: %w", err)
 						}
@@ -148,22 +780,56 @@ func main() {
 					Name:  "chrome",
 					Usage: "Chrome Store",
 					Action: func(c *cli.Context) error {
-						store, err := getChromeStore()
+						store, storeV2, err := getChromeStore()
 						if err != nil {
 							return fmt.Errorf("initializing chrome store: %w", err)
 						}
 
 						appID := c.String("app")
-						status, err := store.Status(appID)
+
+						if storeV2 != nil {
+							status, err := storeV2.Status(appID)
+							if err != nil {
+								return fmt.Errorf("getting status from chrome store: %w", err)
+							}
+
+							printChromeStatusV2(os.Stdout, status)
+
+							return nil
+						}
+
+						if c.IsSet("projection") {
+							projection, err := chrome.ParseProjection(c.String("projection"))
+							if err != nil {
+								return fmt.Errorf("parsing projection: %w", err)
+							}
+
+							status, err := store.Status(appID, projection)
+							if err != nil {
+								return fmt.Errorf("getting status from chrome store: %w", err)
+							}
+
+							printChromeStatus(os.Stdout, status)
+
+							return nil
+						}
+
+						versions, err := store.Versions(appID)
 						if err != nil {
-							return fmt.Errorf("getting status from chrome store: %w", err)
+							return fmt.Errorf("getting versions from chrome store: %w", err)
 						}
 
-						fmt.Printf("%s\n", status)
+						printChromeVersions(os.Stdout, versions)
 
 						return nil
 					},
-					Flags: []cli.Flag{appFlag},
+					Flags: []cli.Flag{
+						appFlag,
+						&cli.StringFlag{
+							Name:  "projection",
+							Usage: "version of the extension to show: draft or published, both if not set",
+						},
+					},
 				},
 			},
 		},
@@ -176,11 +842,15 @@ func main() {
 					Usage: "inserts new extension to the chrome store",
 					Flags: []cli.Flag{fileFlag},
 					Action: func(c *cli.Context) error {
-						store, err := getChromeStore()
+						store, storeV2, err := getChromeStore()
 						if err != nil {
 							return fmt.Errorf("initializing chrome store: %w", err)
 						}
 
+						if storeV2 != nil {
+							return fmt.Errorf("inserting isn't supported by the API %s", chromeAPIV2)
+						}
+
 						filepath := c.String("file")
 
 						result, err := store.Insert(filepath)
@@ -188,7 +858,7 @@ func main() {
 							return fmt.Errorf("inserting extension: %w", err)
 						}
 
-						fmt.Println(result)
+						printResult(c, result)
 
 						return nil
 					},
@@ -199,18 +869,29 @@ func main() {
 					Flags: []cli.Flag{
 						fileFlag,
 						sourceFlag,
+						sourceGitFlag,
+						buildInstructionsFlag,
+						pollIntervalFlag,
+						waitTimeoutFlag,
 					},
 					Action: func(c *cli.Context) error {
-						store, err := getFirefoxStore()
+						store, err := getFirefoxStore(c)
 						if err != nil {
 							return fmt.Errorf("initializing firefox store: %w", err)
 						}
 
 						filepath := c.String("file")
-						sourcepath := c.String("source")
+
+						sourcepath, cleanup, err := firefoxSource(c)
+						if err != nil {
+							return fmt.Errorf("preparing source: %w", err)
+						}
+						defer cleanup()
 
 						err = store.Insert(filepath, sourcepath)
 						if err != nil {
+							printValidationReport(err)
+
 							return fmt.Errorf("inserting extension: %w", err)
 						}
 
@@ -231,7 +912,7 @@ func main() {
 						fileFlag,
 					},
 					Action: func(c *cli.Context) error {
-						store, err := getChromeStore()
+						store, storeV2, err := getChromeStore()
 						if err != nil {
 							return fmt.Errorf("initializing chrome store: %w", err)
 						}
@@ -239,12 +920,23 @@ func main() {
 						filepath := c.String("file")
 						appID := c.String("app")
 
+						if storeV2 != nil {
+							result, err := storeV2.Upload(appID, filepath)
+							if err != nil {
+								return fmt.Errorf("updating extension: %w", err)
+							}
+
+							printResult(c, result)
+
+							return nil
+						}
+
 						result, err := store.Update(appID, filepath)
 						if err != nil {
 							return fmt.Errorf("updating extension: %w", err)
 						}
 
-						fmt.Println(result)
+						printResult(c, result)
 
 						return nil
 					},
@@ -255,18 +947,29 @@ func main() {
 					Flags: []cli.Flag{
 						fileFlag,
 						sourceFlag,
+						sourceGitFlag,
+						buildInstructionsFlag,
+						pollIntervalFlag,
+						waitTimeoutFlag,
 					},
 					Action: func(c *cli.Context) error {
-						store, err := getFirefoxStore()
+						store, err := getFirefoxStore(c)
 						if err != nil {
 							return fmt.Errorf("getting firefox store: %w", err)
 						}
 
 						filepath := c.String("file")
-						sourcepath := c.String("source")
+
+						sourcepath, cleanup, err := firefoxSource(c)
+						if err != nil {
+							return fmt.Errorf("preparing source: %w", err)
+						}
+						defer cleanup()
 
 						err = store.Update(filepath, sourcepath)
 						if err != nil {
+							printValidationReport(err)
+
 							return fmt.Errorf("updating extension: %w", err)
 						}
 
@@ -294,7 +997,7 @@ func main() {
 							return fmt.Errorf("updating extension: %w", err)
 						}
 
-						fmt.Println(result)
+						printResult(c, result)
 
 						return nil
 					},
@@ -310,21 +1013,51 @@ func main() {
 					Usage: "publishes extension in the chrome store",
 					Flags: []cli.Flag{
 						appFlag,
+						&cli.StringFlag{
+							Name:  "target",
+							Usage: "audience of the published extension: default or trustedTesters",
+							Value: string(chrome.PublishTargetDefault),
+						},
+						&cli.IntFlag{
+							Name:  "deploy-percentage",
+							Usage: "percentage of users who receive the published version, all users if not set",
+						},
 					},
 					Action: func(c *cli.Context) error {
-						store, err := getChromeStore()
+						store, storeV2, err := getChromeStore()
 						if err != nil {
 							return fmt.Errorf("initializing chrome store: %w", err)
 						}
 
 						appID := c.String("app")
 
-						result, err := store.Publish(appID)
+						target, err := chrome.ParsePublishTarget(c.String("target"))
+						if err != nil {
+							return fmt.Errorf("parsing target: %w", err)
+						}
+
+						publishOptions := chrome.PublishOptions{
+							Target:           target,
+							DeployPercentage: c.Int("deploy-percentage"),
+						}
+
+						if storeV2 != nil {
+							result, err := storeV2.Publish(appID, publishOptions)
+							if err != nil {
+								return fmt.Errorf("publishing extension: %w", err)
+							}
+
+							printResult(c, result)
+
+							return nil
+						}
+
+						result, err := store.Publish(appID, publishOptions)
 						if err != nil {
 							return fmt.Errorf("publishing extension: %w", err)
 						}
 
-						fmt.Println(result)
+						printResult(c, result)
 
 						return nil
 					},
@@ -348,7 +1081,82 @@ func main() {
 							return fmt.Errorf("publishing extension: %w", err)
 						}
 
-						fmt.Println(result)
+						printResult(c, result)
+
+						return nil
+					},
+				},
+			},
+		},
+		{
+			Name:  "rollout",
+			Usage: "changes rollout percentage of the published extension",
+			Subcommands: []*cli.Command{
+				{
+					Name:  "chrome",
+					Usage: "raises rollout percentage of the extension in the chrome store",
+					Flags: []cli.Flag{
+						appFlag,
+						&cli.IntFlag{
+							Name:     "deploy-percentage",
+							Usage:    "percentage of users who receive the published version",
+							Required: true,
+						},
+					},
+					Action: func(c *cli.Context) error {
+						store, storeV2, err := getChromeStore()
+						if err != nil {
+							return fmt.Errorf("initializing chrome store: %w", err)
+						}
+
+						appID := c.String("app")
+						percentage := c.Int("deploy-percentage")
+
+						if storeV2 != nil {
+							err = storeV2.SetDeployPercentage(appID, percentage)
+							if err != nil {
+								return fmt.Errorf("changing rollout percentage: %w", err)
+							}
+
+							return nil
+						}
+
+						result, err := store.SetDeployPercentage(appID, percentage)
+						if err != nil {
+							return fmt.Errorf("changing rollout percentage: %w", err)
+						}
+
+						printResult(c, result)
+
+						return nil
+					},
+				},
+			},
+		},
+		{
+			Name:  "cancel",
+			Usage: "cancels submission of extension which is pending review",
+			Subcommands: []*cli.Command{
+				{
+					Name:  "chrome",
+					Usage: "cancels submission in the chrome store, requires the API v2",
+					Flags: []cli.Flag{
+						appFlag,
+					},
+					Action: func(c *cli.Context) error {
+						_, storeV2, err := getChromeStore()
+						if err != nil {
+							return fmt.Errorf("initializing chrome store: %w", err)
+						}
+
+						if storeV2 == nil {
+							return fmt.Errorf("cancelling is supported by the API %s only", chromeAPIV2)
+						}
+
+						err = storeV2.CancelSubmission(c.String("app"))
+						if err != nil {
+							return fmt.Errorf("cancelling submission: %w", err)
+						}
 
 						return nil
 					},
@@ -364,17 +1172,42 @@ func main() {
 					Usage: "signs extension in the firefox store",
 					Flags: []cli.Flag{
 						fileFlag,
+						pollIntervalFlag,
+						waitTimeoutFlag,
+						&cli.BoolFlag{
+							Name:  "await-review",
+							Usage: "wait for a human review if the extension can't be signed automatically",
+						},
+						&cli.DurationFlag{
+							Name:  "review-poll-interval",
+							Usage: "interval between status checks while waiting for a human review",
+							Value: firefox.DefaultReviewPollInterval,
+						},
+						&cli.DurationFlag{
+							Name:  "review-timeout",
+							Usage: "maximum time to wait for a human review",
+							Value: firefox.DefaultReviewWaitTimeout,
+						},
+						&cli.BoolFlag{
+							Name:  "resume",
+							Usage: "don't upload the extension if this version is already uploaded",
+						},
 					},
 					Action: func(c *cli.Context) error {
-						store, err := getFirefoxStore()
+						store, err := getFirefoxStore(c)
 						if err != nil {
 							return fmt.Errorf("getting firefox store: %w", err)
 						}
 
 						filepath := c.String("file")
 
-						err = store.Sign(filepath)
+						err = store.Sign(filepath, firefox.SignOptions{
+							AwaitReview: c.Bool("await-review"),
+							Resume:      c.Bool("resume"),
+						})
 						if err != nil {
+							printValidationReport(err)
+
 							return fmt.Errorf("signing extension: %w", err)
 						}
 
@@ -385,6 +1218,8 @@ func main() {
 		},
 	}
 
+	addReleaseEvents(app.Commands)
+
 	err := app.Run(os.Args)
 	if err != nil {
 		log.Fatalf("failed to run app: %s", err)
@@ -0,0 +1,275 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/template"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/log"
+	"github.com/maximtop/extdash/internal/notify"
+	"github.com/urfave/cli/v2"
+)
+
+// Notifier types in the config file.
+const (
+	notifierSlack   = "slack"
+	notifierWebhook = "webhook"
+	notifierEmail   = "email"
+)
+
+// notifierConfig describes the notifier in the config file.  Environment
+// variables in the URL, the secret and the password, e.g. $SLACK_WEBHOOK_URL,
+// are expanded, so they can be kept out of the config file.
+type notifierConfig struct {
+	// Name is used to route the alerts to the notifier.
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	URL      string `json:"url"`
+	Secret   string `json:"secret"`
+	Header   string `json:"signature_header"`
+	Template string `json:"template"`
+
+	// Addr is the address of the SMTP server for the email notifier.
+	Addr     string   `json:"addr"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	Security string   `json:"security"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	Subject  string   `json:"subject"`
+
+	// Events are the types of the events sent to the notifier, all events are
+	// sent if empty.
+	Events []notify.EventType `json:"events"`
+}
+
+// parseOptionalTemplate parses the template if text isn't empty.
+func parseOptionalTemplate(name, text string) (tmpl *template.Template, err error) {
+	if text == "" {
+		return nil, nil
+	}
+
+	tmpl, err = notify.ParseTemplate(name, text)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s template: %w", name, err)
+	}
+
+	return tmpl, nil
+}
+
+// newEmailNotifier creates the email notifier from the config.
+func newEmailNotifier(cfg notifierConfig, body *template.Template) (n *notify.Email, err error) {
+	if cfg.Addr == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return nil, fmt.Errorf("addr, from and to are required")
+	}
+
+	subject, err := parseOptionalTemplate("subject", cfg.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return &notify.Email{
+		Addr:     cfg.Addr,
+		Username: os.ExpandEnv(cfg.Username),
+		Password: os.ExpandEnv(cfg.Password),
+		From:     cfg.From,
+		To:       cfg.To,
+		Subject:  subject,
+		Body:     body,
+		Security: notify.EmailSecurity(cfg.Security),
+	}, nil
+}
+
+// newNotifier creates the notifier from the config.
+func newNotifier(cfg notifierConfig) (n notify.Notifier, err error) {
+	tmpl, err := parseOptionalTemplate(cfg.Type, cfg.Template)
+	if err != nil {
+		return nil, err
+	}
+
+	url := os.ExpandEnv(cfg.URL)
+	if url == "" && cfg.Type != notifierEmail {
+		return nil, fmt.Errorf("url is required")
+	}
+
+	switch cfg.Type {
+	case notifierSlack:
+		n = &notify.Slack{URL: url, Template: tmpl}
+	case notifierEmail:
+		n, err = newEmailNotifier(cfg, tmpl)
+		if err != nil {
+			return nil, err
+		}
+	case notifierWebhook:
+		n = &notify.Webhook{
+			URL:             url,
+			Secret:          []byte(os.ExpandEnv(cfg.Secret)),
+			SignatureHeader: cfg.Header,
+			Template:        tmpl,
+		}
+	default:
+		return nil, fmt.Errorf("unknown notifier type %q", cfg.Type)
+	}
+
+	return &notify.Filter{Notifier: n, Types: cfg.Events}, nil
+}
+
+// newNotifiers creates the notifiers from the config by name, the unnamed
+// notifiers are named by their index.
+func newNotifiers(cfg *appConfig) (notifiers map[string]notify.Notifier, err error) {
+	notifiers = map[string]notify.Notifier{}
+
+	for i, notifierCfg := range cfg.Notifiers {
+		name := notifierCfg.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i)
+		}
+
+		if _, ok := notifiers[name]; ok {
+			return nil, fmt.Errorf("notifier %q is configured twice", name)
+		}
+
+		notifiers[name], err = newNotifier(notifierCfg)
+		if err != nil {
+			return nil, fmt.Errorf("notifier %q: %w", name, err)
+		}
+	}
+
+	return notifiers, nil
+}
+
+// allNotifiers joins the notifiers.
+func allNotifiers(notifiers map[string]notify.Notifier) (n notify.Multi) {
+	for _, notifier := range notifiers {
+		n = append(n, notifier)
+	}
+
+	return n
+}
+
+// extensionName returns the name of the configured extension by its ID in the
+// store, or an empty string.  cfg may be nil.
+func (cfg *appConfig) extensionName(store, appID string) (name string) {
+	if cfg == nil {
+		return ""
+	}
+
+	for name, ext := range cfg.Extensions {
+		if appID != "" && ext.storeIDs()[store] == appID {
+			return name
+		}
+	}
+
+	return ""
+}
+
+// releaseNotifiers returns the notifiers configured in the config file, or nil
+// if there is no config file.
+func releaseNotifiers(c *cli.Context) (cfg *appConfig, n notify.Notifier, err error) {
+	configPath := c.String("config")
+	if configPath == "" {
+		return nil, nil, nil
+	}
+
+	cfg, err = readConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	notifiers, err := newNotifiers(cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(notifiers) == 0 {
+		return cfg, nil, nil
+	}
+
+	return cfg, allNotifiers(notifiers), nil
+}
+
+// withReleaseEvents wraps the action of the release command, so that the
+// outcome is recorded to the history and the configured notifiers receive it.
+// The recording and the notification errors are logged and don't affect the
+// command result.
+func withReleaseEvents(store, operation string, action cli.ActionFunc) (wrapped cli.ActionFunc) {
+	return func(c *cli.Context) (err error) {
+		cfg, notifier, err := releaseNotifiers(c)
+		if err != nil {
+			return fmt.Errorf("creating notifiers: %w", err)
+		}
+
+		err = action(c)
+
+		manifest := readManifest(c.String("file"))
+		event := &notify.Event{
+			Time:      time.Now(),
+			Type:      notify.EventReleaseSucceeded,
+			Store:     store,
+			AppID:     c.String("app"),
+			Operation: operation,
+		}
+
+		// firefox commands take the add-on ID from the manifest
+		if event.AppID == "" && store == "firefox" {
+			event.AppID = manifest.geckoID()
+		}
+
+		event.Extension = cfg.extensionName(store, event.AppID)
+		event.Version = releaseVersion(c.String("history"), manifest, store, event.AppID)
+
+		if err != nil {
+			event.Type = notify.EventReleaseFailed
+			event.Message = err.Error()
+		}
+
+		recordRelease(c, event)
+
+		if notifier == nil {
+			return err
+		}
+
+		notifyErr := notifier.Notify(context.Background(), event)
+		if notifyErr != nil {
+			log.Error("sending release notification: %s", notifyErr)
+		}
+
+		return err
+	}
+}
+
+// releaseCommands contains the names of the commands, which subcommands
+// release the extensions.
+var releaseCommands = []string{"insert", "update", "publish", "rollout", "cancel", "sign"}
+
+// addReleaseEvents wraps the actions of the release subcommands with
+// withReleaseEvents.
+func addReleaseEvents(commands []*cli.Command) {
+	for _, command := range commands {
+		if !isReleaseCommand(command.Name) {
+			continue
+		}
+
+		for _, sub := range command.Subcommands {
+			if sub.Action != nil {
+				sub.Action = withReleaseEvents(sub.Name, command.Name, sub.Action)
+			}
+		}
+	}
+}
+
+// isReleaseCommand returns true if the command is one of releaseCommands.
+func isReleaseCommand(name string) (ok bool) {
+	for _, command := range releaseCommands {
+		if command == name {
+			return true
+		}
+	}
+
+	return false
+}
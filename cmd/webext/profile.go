@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/urfave/cli/v2"
+)
+
+// profileStores contains names of the stores which settings can be set in the
+// profile.
+var profileStores = []string{"chrome", "firefox", "edge"}
+
+// profile contains the settings of the stores by store name.  The settings are
+// named after the environment variables without the store prefix, e.g.
+// "client_id" in the "chrome" section sets CHROME_CLIENT_ID.
+type profile map[string]map[string]string
+
+// env returns the environment variables set by the profile.
+func (p profile) env() (vars map[string]string, err error) {
+	vars = map[string]string{}
+
+	for store, settings := range p {
+		if !isProfileStore(store) {
+			return nil, fmt.Errorf("unknown store %q", store)
+		}
+
+		for name, value := range settings {
+			vars[strings.ToUpper(store+"_"+name)] = value
+		}
+	}
+
+	return vars, nil
+}
+
+// isProfileStore returns true if the store settings can be set in the profile.
+func isProfileStore(store string) (ok bool) {
+	for _, s := range profileStores {
+		if s == store {
+			return true
+		}
+	}
+
+	return false
+}
+
+// selectProfile returns the profile selected by the profile flag or by the
+// default_profile of the config, explicit is true if it's selected by the flag.
+// It returns nil if no profile is selected and the config file doesn't exist.
+func selectProfile(c *cli.Context) (p profile, explicit bool, err error) {
+	configPath := c.String("config")
+	name := c.String("profile")
+	explicit = name != ""
+
+	if configPath == "" {
+		if explicit {
+			return nil, false, fmt.Errorf("profile %q is selected, but config path is not set", name)
+		}
+
+		return nil, false, nil
+	}
+
+	cfg, err := readConfig(configPath)
+	if errors.Is(err, os.ErrNotExist) && !explicit && !c.IsSet("config") {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	if !explicit {
+		name = cfg.DefaultProfile
+	}
+
+	if name == "" {
+		return nil, false, nil
+	}
+
+	p, ok := cfg.Profiles[name]
+	if !ok {
+		return nil, false, fmt.Errorf("profile %q is not found in %q", name, configPath)
+	}
+
+	_, err = p.env()
+	if err != nil {
+		return nil, false, fmt.Errorf("profile %q: %w", name, err)
+	}
+
+	return p, explicit, nil
+}
+
+// pinned returns names of the environment variables which belong to the stores
+// of the profile: the variables set by the profile and the credentials of its
+// stores.
+func (p profile) pinned() (names map[string]bool, err error) {
+	vars, err := p.env()
+	if err != nil {
+		return nil, err
+	}
+
+	names = make(map[string]bool, len(vars))
+	for name := range vars {
+		names[name] = true
+	}
+
+	for store := range p {
+		prefix := strings.ToUpper(store) + "_"
+		for _, name := range secretVars {
+			if strings.HasPrefix(name, prefix) {
+				names[name] = true
+			}
+		}
+	}
+
+	return names, nil
+}
+
+// pinProfile sets the environment variables from the explicitly selected
+// profile, overriding the .env file, and unsets the credentials of its stores
+// which the profile doesn't set, so that the accounts aren't mixed.  Variables
+// set in the process environment, listed in processEnv, are kept.  It returns
+// names of the variables which the secret sources must not set.
+func pinProfile(p profile, processEnv map[string]bool) (pinned map[string]bool, err error) {
+	vars, err := p.env()
+	if err != nil {
+		return nil, err
+	}
+
+	pinned, err = p.pinned()
+	if err != nil {
+		return nil, err
+	}
+
+	for name := range pinned {
+		if processEnv[name] {
+			continue
+		}
+
+		if value, ok := vars[name]; ok {
+			err = os.Setenv(name, value)
+		} else {
+			err = os.Unsetenv(name)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("setting %s: %w", name, err)
+		}
+	}
+
+	return pinned, nil
+}
+
+// listProfiles prints names of the profiles in the config file, the default
+// profile is marked with an asterisk.
+func listProfiles(c *cli.Context) (err error) {
+	cfg, err := readConfig(c.String("config"))
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		mark := " "
+		if name == cfg.DefaultProfile {
+			mark = "*"
+		}
+
+		stores := make([]string, 0, len(cfg.Profiles[name]))
+		for store := range cfg.Profiles[name] {
+			stores = append(stores, store)
+		}
+
+		sort.Strings(stores)
+
+		fmt.Printf("%s %s\t%s\n", mark, name, strings.Join(stores, ", "))
+	}
+
+	return nil
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/urfave/cli/v2"
+)
+
+// testConfig is the config with the profiles used in the tests.
+const testConfig = `{
+  "default_profile": "default",
+  "profiles": {
+    "default": {
+      "chrome": {"client_id": "default-id", "client_secret": "default-secret"}
+    },
+    "beta": {
+      "chrome": {"client_id": "beta-id", "client_secret": "beta-secret"}
+    }
+  }
+}`
+
+// newTestContext returns the context with the global flags used by
+// loadCredentials.  The helper is the script printing its output.
+func newTestContext(t *testing.T, profileName, helperOutput string) (c *cli.Context) {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	configPath := filepath.Join(dir, "config.json")
+	err := os.WriteFile(configPath, []byte(testConfig), 0o600)
+	require.NoError(t, err)
+
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	set.String("config", configPath, "")
+	set.String("profile", profileName, "")
+	set.Bool("secrets-stdin", false, "")
+	set.String("credential-helper", "", "")
+	set.String("vault", "", "")
+
+	if helperOutput != "" {
+		outputPath := filepath.Join(dir, "output")
+		err = os.WriteFile(outputPath, []byte(helperOutput), 0o600)
+		require.NoError(t, err)
+
+		require.NoError(t, set.Set("credential-helper", "cat "+outputPath))
+	}
+
+	return cli.NewContext(nil, set, nil)
+}
+
+// clearSecretEnv unsets the secret variables for the duration of the test.
+func clearSecretEnv(t *testing.T) {
+	t.Helper()
+
+	for _, name := range secretVars {
+		t.Setenv(name, "")
+		require.NoError(t, os.Unsetenv(name))
+	}
+}
+
+func TestLoadCredentials(t *testing.T) {
+	testCases := []struct {
+		name    string
+		profile string
+		// dotEnv are the variables set after the process start, e.g. from the
+		// .env file.
+		dotEnv map[string]string
+		// processEnv are the variables set in the process environment.
+		processEnv   map[string]string
+		helperOutput string
+		want         map[string]string
+	}{{
+		name:    "profile overrides dotenv",
+		profile: "beta",
+		dotEnv:  map[string]string{"CHROME_CLIENT_ID": "dotenv-id"},
+		want: map[string]string{
+			"CHROME_CLIENT_ID":     "beta-id",
+			"CHROME_CLIENT_SECRET": "beta-secret",
+		},
+	}, {
+		name:         "profile overrides helper",
+		profile:      "beta",
+		helperOutput: "CHROME_CLIENT_SECRET=helper-secret\n",
+		want: map[string]string{
+			"CHROME_CLIENT_ID":     "beta-id",
+			"CHROME_CLIENT_SECRET": "beta-secret",
+		},
+	}, {
+		name:       "process env overrides profile",
+		profile:    "beta",
+		processEnv: map[string]string{"CHROME_CLIENT_ID": "env-id"},
+		want: map[string]string{
+			"CHROME_CLIENT_ID":     "env-id",
+			"CHROME_CLIENT_SECRET": "beta-secret",
+		},
+	}, {
+		name:         "no fallback for profile store",
+		profile:      "beta",
+		dotEnv:       map[string]string{"CHROME_REFRESH_TOKEN": "dotenv-token"},
+		helperOutput: "CHROME_REFRESH_TOKEN=helper-token\nFIREFOX_CLIENT_ID=helper-id\n",
+		want: map[string]string{
+			"CHROME_CLIENT_ID":     "beta-id",
+			"CHROME_CLIENT_SECRET": "beta-secret",
+			"FIREFOX_CLIENT_ID":    "helper-id",
+		},
+	}, {
+		name:         "default profile is the last",
+		dotEnv:       map[string]string{"CHROME_CLIENT_ID": "dotenv-id"},
+		helperOutput: "CHROME_REFRESH_TOKEN=helper-token\n",
+		want: map[string]string{
+			"CHROME_CLIENT_ID":     "dotenv-id",
+			"CHROME_CLIENT_SECRET": "default-secret",
+			"CHROME_REFRESH_TOKEN": "helper-token",
+		},
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			clearSecretEnv(t)
+
+			processEnv := map[string]bool{}
+			for name, value := range tc.processEnv {
+				processEnv[name] = true
+				t.Setenv(name, value)
+			}
+
+			for name, value := range tc.dotEnv {
+				t.Setenv(name, value)
+			}
+
+			c := newTestContext(t, tc.profile, tc.helperOutput)
+			err := loadCredentials(c, processEnv)
+			require.NoError(t, err)
+
+			got := map[string]string{}
+			for _, name := range secretVars {
+				if value, ok := os.LookupEnv(name); ok {
+					got[name] = value
+				}
+			}
+
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestLoadCredentials_unknownProfile(t *testing.T) {
+	clearSecretEnv(t)
+
+	c := newTestContext(t, "unknown", "")
+	err := loadCredentials(c, nil)
+	assert.ErrorContains(t, err, `profile "unknown" is not found`)
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/joho/godotenv"
+	"github.com/urfave/cli/v2"
+)
+
+// secretVars contains names of the environment variables with the store
+// credentials, which can also be read from the secret sources.
+var secretVars = []string{
+	"CHROME_CLIENT_ID",
+	"CHROME_CLIENT_SECRET",
+	"CHROME_REFRESH_TOKEN",
+	"FIREFOX_CLIENT_ID",
+	"FIREFOX_CLIENT_SECRET",
+	"EDGE_CLIENT_ID",
+	"EDGE_CLIENT_SECRET",
+	"EDGE_ACCESS_TOKEN_URL",
+}
+
+// envNames returns names of the variables set in the process environment.
+func envNames() (names map[string]bool) {
+	names = map[string]bool{}
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		names[name] = true
+	}
+
+	return names
+}
+
+// setUnsetEnv sets the environment variables from vars, which are not set yet
+// and are not pinned.
+func setUnsetEnv(vars map[string]string, pinned map[string]bool) (err error) {
+	for key, value := range vars {
+		if _, ok := os.LookupEnv(key); ok || pinned[key] {
+			continue
+		}
+
+		err = os.Setenv(key, value)
+		if err != nil {
+			return fmt.Errorf("setting %s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// readSecretFiles returns the values of the secret variables read from the
+// files set in the variables with the _FILE suffix, e.g. CHROME_CLIENT_SECRET
+// is read from the file at CHROME_CLIENT_SECRET_FILE.  Trailing newlines are
+// trimmed.
+func readSecretFiles() (vars map[string]string, err error) {
+	vars = map[string]string{}
+
+	for _, name := range secretVars {
+		secretFile := os.Getenv(name + "_FILE")
+		if secretFile == "" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Clean(secretFile))
+		if err != nil {
+			return nil, fmt.Errorf("reading %s_FILE: %w", name, err)
+		}
+
+		vars[name] = strings.TrimRight(string(data), "\r\n")
+	}
+
+	return vars, nil
+}
+
+// parseHelperOutput parses the output of the credential helper, which is either
+// the JSON object with the variable names as keys or the KEY=VALUE lines.  The
+// values are taken verbatim up to the end of the line, so they may contain
+// spaces and "=".  Empty lines and lines starting with "#" are skipped.
+func parseHelperOutput(output []byte) (vars map[string]string, err error) {
+	if bytes.HasPrefix(bytes.TrimSpace(output), []byte("{")) {
+		err = json.Unmarshal(output, &vars)
+		if err != nil {
+			return nil, fmt.Errorf("unmarshaling: %w", err)
+		}
+
+		return vars, nil
+	}
+
+	vars = map[string]string{}
+	for i, line := range strings.Split(string(output), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", i+1)
+		}
+
+		vars[key] = value
+	}
+
+	return vars, nil
+}
+
+// runCredentialHelper runs the credential helper command and returns the
+// variables from its output, see parseHelperOutput.  Names of the requested
+// variables are passed to the helper's stdin one per line.
+func runCredentialHelper(command string) (vars map[string]string, err error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty credential helper command")
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	// #nosec G204 -- the command is set by the user explicitly.
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = strings.NewReader(strings.Join(secretVars, "\n") + "\n")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if err != nil {
+		return nil, fmt.Errorf("running %q: %w, stderr: %q", args[0], err, stderr.String())
+	}
+
+	vars, err = parseHelperOutput(stdout.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("parsing output of %q: %w", args[0], err)
+	}
+
+	return vars, nil
+}
+
+// resolveSecrets sets the store credentials which are not set in the
+// environment or in the .env file and are not pinned from the secret sources.
+// The sources are checked in order: _FILE variables, stdin if the
+// secrets-stdin flag is set, and the credential helper.
+func resolveSecrets(c *cli.Context, pinned map[string]bool) (err error) {
+	vars, err := readSecretFiles()
+	if err != nil {
+		return err
+	}
+
+	err = setUnsetEnv(vars, pinned)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("secrets-stdin") {
+		vars, err = godotenv.Parse(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("parsing secrets from stdin: %w", err)
+		}
+
+		err = setUnsetEnv(vars, pinned)
+		if err != nil {
+			return err
+		}
+	}
+
+	if helper := c.String("credential-helper"); helper != "" {
+		vars, err = runCredentialHelper(helper)
+		if err != nil {
+			return fmt.Errorf("getting credentials from helper: %w", err)
+		}
+
+		err = setUnsetEnv(vars, pinned)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadCredentials sets the store credentials from all sources.  The variables
+// of the process environment, listed in processEnv, take precedence.  The
+// explicitly selected profile overrides the other sources for the variables of
+// its stores, see pinProfile.  The .env file and the secret sources follow,
+// and the default profile is used for the variables which are still unset.
+// The vault is opened later, when a store client needs a credential which no
+// source provided, see lazyVault.
+func loadCredentials(c *cli.Context, processEnv map[string]bool) (err error) {
+	p, explicit, err := selectProfile(c)
+	if err != nil {
+		return err
+	}
+
+	var pinned map[string]bool
+	if explicit {
+		pinned, err = pinProfile(p, processEnv)
+		if err != nil {
+			return fmt.Errorf("applying profile: %w", err)
+		}
+	}
+
+	err = resolveSecrets(c, pinned)
+	if err != nil {
+		return fmt.Errorf("resolving secrets: %w", err)
+	}
+
+	// the creds commands open the vault themselves
+	if c.Args().First() != "creds" {
+		credsVault, err = newLazyVault(c, pinned)
+		if err != nil {
+			return err
+		}
+	}
+
+	if p == nil || explicit {
+		return nil
+	}
+
+	vars, err := p.env()
+	if err != nil {
+		return fmt.Errorf("applying profile: %w", err)
+	}
+
+	return setUnsetEnv(vars, nil)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHelperOutput(t *testing.T) {
+	testCases := []struct {
+		name    string
+		output  string
+		want    map[string]string
+		wantErr string
+	}{{
+		name:   "lines",
+		output: "# comment\nCHROME_CLIENT_SECRET=secret with spaces\r\n\nEDGE_ACCESS_TOKEN_URL=https://example.org/token?a=b\n",
+		want: map[string]string{
+			"CHROME_CLIENT_SECRET":  "secret with spaces",
+			"EDGE_ACCESS_TOKEN_URL": "https://example.org/token?a=b",
+		},
+	}, {
+		name:   "json",
+		output: `{"CHROME_CLIENT_SECRET": "secret with spaces"}`,
+		want:   map[string]string{"CHROME_CLIENT_SECRET": "secret with spaces"},
+	}, {
+		name:    "invalid line",
+		output:  "CHROME_CLIENT_SECRET=secret\nsecret\n",
+		wantErr: "line 2: expected KEY=VALUE",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			vars, err := parseHelperOutput([]byte(tc.output))
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, vars)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/log"
+	"github.com/maximtop/extdash/internal/api"
+	"github.com/maximtop/extdash/internal/dashboard"
+	"github.com/maximtop/extdash/internal/metrics"
+	"github.com/maximtop/extdash/internal/watch"
+	"github.com/urfave/cli/v2"
+)
+
+// defaultServeAddr is the default address of the dashboard server, it is only
+// reachable locally.
+const defaultServeAddr = "127.0.0.1:8080"
+
+// Timeouts of the dashboard server.
+const (
+	readHeaderTimeout = 10 * time.Second
+	shutdownTimeout   = 10 * time.Second
+)
+
+// newDashboard creates the dashboard of the configured extensions selected by
+// the command arguments.
+func newDashboard(c *cli.Context, cfg *appConfig) (d *dashboard.Dashboard, err error) {
+	names, err := cfg.selectExtensions(c.Args().Slice())
+	if err != nil {
+		return nil, err
+	}
+
+	notifier, err := statusNotifier(c, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := alertRules(c, cfg, names)
+	if err != nil {
+		return nil, err
+	}
+
+	extensions := map[string]map[string]string{}
+	for _, name := range names {
+		extensions[name] = cfg.Extensions[name].storeIDs()
+	}
+
+	// the errors of the stores are counted for the metrics
+	errs := &metrics.Errors{}
+
+	targets := statusTargets(c, cfg, names)
+	for _, t := range targets {
+		t.Source = &metrics.Source{Source: t.Source, Errors: errs, Store: t.Store}
+	}
+
+	collectors := statsTargets(c, cfg, names)
+	for _, t := range collectors {
+		t.Collector = &metrics.Collector{Collector: t.Collector, Errors: errs, Store: t.Store}
+	}
+
+	return &dashboard.Dashboard{
+		Extensions: extensions,
+		Watcher: &watch.Watcher{
+			Targets:   targets,
+			Notifier:  notifier,
+			StatePath: c.String("state"),
+			Rules:     rules,
+		},
+		StatsTargets:  collectors,
+		HistoryPath:   c.String("history"),
+		Interval:      c.Duration("interval"),
+		StatsInterval: c.Duration("stats-interval"),
+		Errors:        errs,
+	}, nil
+}
+
+// serve runs the dashboard server until it is interrupted.
+func serve(c *cli.Context) (err error) {
+	cfg, err := readConfig(c.String("config"))
+	if err != nil {
+		return err
+	}
+
+	d, err := newDashboard(c, cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	mux := http.NewServeMux()
+	mux.Handle("/", d)
+	mux.HandleFunc("/metrics", d.ServeMetrics)
+
+	if token := c.String("api-token"); token != "" {
+		notifiers, err := newNotifiers(cfg)
+		if err != nil {
+			return fmt.Errorf("creating notifiers: %w", err)
+		}
+
+		executor := newAPIExecutor(c, cfg, allNotifiers(notifiers))
+		mux.Handle(api.PathPrefix, &api.API{
+			Jobs:    &api.Jobs{Executor: metrics.Executor(executor, d.Errors)},
+			Token:   token,
+			Context: ctx,
+		})
+	}
+
+	srv := &http.Server{
+		Addr:              c.String("addr"),
+		Handler:           mux,
+		ReadHeaderTimeout: readHeaderTimeout,
+	}
+
+	go d.Run(ctx)
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer shutdownCancel()
+
+		shutdownErr := srv.Shutdown(shutdownCtx)
+		if shutdownErr != nil {
+			log.Error("shutting down server: %s", shutdownErr)
+		}
+	}()
+
+	log.Info("serving dashboard on http://%s", srv.Addr)
+
+	err = srv.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serving: %w", err)
+	}
+
+	return nil
+}
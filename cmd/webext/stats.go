@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/log"
+	"github.com/maximtop/extdash/internal/stats"
+	"github.com/urfave/cli/v2"
+)
+
+// Formats of the statistics export.
+const (
+	formatCSV  = "csv"
+	formatJSON = "json"
+)
+
+// defaultStatsPath returns path to the collected statistics in the user config
+// directory, e.g. ~/.config/extdash/stats.jsonl.
+func defaultStatsPath() (statsPath string) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "extdash", "stats.jsonl")
+}
+
+// newStatsCollector creates the statistics collector for the store.
+func newStatsCollector(c *cli.Context, store string) (collector stats.Collector, err error) {
+	switch store {
+	case "chrome":
+		return &stats.ChromeCollector{}, nil
+	case "firefox":
+		firefoxStore, err := getFirefoxStore(c)
+		if err != nil {
+			return nil, err
+		}
+
+		return &stats.FirefoxCollector{Store: firefoxStore}, nil
+	case "edge":
+		return &stats.EdgeCollector{}, nil
+	default:
+		return nil, fmt.Errorf("unknown store %q", store)
+	}
+}
+
+// statsIDs returns the IDs used to collect the statistics of the extension by
+// store name.  Edge statistics are found by the CRX ID.
+func (e extensionConfig) statsIDs() (ids map[string]string) {
+	ids = e.storeIDs()
+	delete(ids, "edge")
+
+	if e.EdgeCRX != "" {
+		ids["edge"] = e.EdgeCRX
+	}
+
+	return ids
+}
+
+// statsTargets returns the targets for the configured extensions selected by
+// the names.  The stores, which collectors can't be created, are skipped with a
+// warning.
+func statsTargets(c *cli.Context, cfg *appConfig, names []string) (targets []*stats.Target) {
+	collectors := map[string]stats.Collector{}
+
+	for _, name := range names {
+		ids := cfg.Extensions[name].statsIDs()
+
+		stores := make([]string, 0, len(ids))
+		for store := range ids {
+			stores = append(stores, store)
+		}
+		sort.Strings(stores)
+
+		for _, store := range stores {
+			collector, ok := collectors[store]
+			if !ok {
+				var err error
+				collector, err = newStatsCollector(c, store)
+				if err != nil {
+					log.Info("skipping %s: %s", store, err)
+				}
+
+				collectors[store] = collector
+			}
+
+			if collector == nil {
+				continue
+			}
+
+			targets = append(targets, &stats.Target{
+				Extension: name,
+				Store:     store,
+				AppID:     ids[store],
+				Collector: collector,
+			})
+		}
+	}
+
+	return targets
+}
+
+// collectStats collects the statistics of the configured extensions selected
+// by the command arguments, prints them, appends them to the stats file and
+// records them to the history.  The samples, which were collected, are saved
+// even if some stores fail.
+func collectStats(c *cli.Context) (err error) {
+	cfg, err := readConfig(c.String("config"))
+	if err != nil {
+		return err
+	}
+
+	names, err := cfg.selectExtensions(c.Args().Slice())
+	if err != nil {
+		return err
+	}
+
+	samples, collectErr := stats.Collect(statsTargets(c, cfg, names), time.Now())
+	if len(samples) == 0 {
+		return collectErr
+	}
+
+	printSamples(os.Stdout, samples)
+
+	var errs []error
+	if collectErr != nil {
+		errs = append(errs, collectErr)
+	}
+
+	file := &stats.File{Path: c.String("stats")}
+
+	err = file.Append(samples)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("saving samples: %w", err))
+	}
+
+	err = recordSamples(c, samples)
+	if err != nil {
+		errs = append(errs, fmt.Errorf("recording samples to history: %w", err))
+	}
+
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return errors.List("collecting stats", errs...)
+	}
+}
+
+// printSamples prints the samples as the table.
+func printSamples(output io.Writer, samples []*stats.Sample) {
+	w := tabwriter.NewWriter(output, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "extension\tstore\tversion\tusers\trating\tratings\treviews\tweekly downloads\n")
+	for _, s := range samples {
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%d\t%.2f\t%d\t%d\t%d\n",
+			s.Extension,
+			s.Store,
+			s.Version,
+			s.Users,
+			s.Rating,
+			s.RatingCount,
+			s.ReviewCount,
+			s.WeeklyDownloads,
+		)
+	}
+
+	_ = w.Flush()
+}
+
+// exportStats writes the collected statistics selected by the flags to the
+// output file or stdout.
+func exportStats(c *cli.Context) (err error) {
+	filter := &stats.Filter{
+		Extension: c.String("extension"),
+		Store:     c.String("store"),
+	}
+
+	if c.IsSet("since") {
+		filter.Since = time.Now().Add(-c.Duration("since"))
+	}
+
+	var write func(w io.Writer, samples []*stats.Sample) (err error)
+	switch format := c.String("format"); format {
+	case formatCSV:
+		write = stats.WriteCSV
+	case formatJSON:
+		write = stats.WriteJSON
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+
+	file := &stats.File{Path: c.String("stats")}
+
+	samples, err := file.Read(filter)
+	if err != nil {
+		return err
+	}
+
+	output := c.String("output")
+	if output == "" {
+		return write(os.Stdout, samples)
+	}
+
+	f, err := os.Create(filepath.Clean(output))
+	if err != nil {
+		return fmt.Errorf("creating output: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, f.Close()) }()
+
+	return write(f, samples)
+}
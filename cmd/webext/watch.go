@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/AdguardTeam/golibs/log"
+	"github.com/maximtop/extdash/internal/history"
+	"github.com/maximtop/extdash/internal/notify"
+	"github.com/maximtop/extdash/internal/watch"
+	"github.com/urfave/cli/v2"
+)
+
+// defaultStatePath returns path to the watcher state in the user config
+// directory, e.g. ~/.config/extdash/watch.json.
+func defaultStatePath() (statePath string) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(dir, "extdash", "watch.json")
+}
+
+// newWatchSource creates the status source for the store.
+func newWatchSource(c *cli.Context, store string) (source watch.Source, err error) {
+	switch store {
+	case "chrome":
+		chromeStore, chromeStoreV2, err := getChromeStore()
+		if err != nil {
+			return nil, err
+		}
+
+		if chromeStoreV2 != nil {
+			return &watch.ChromeV2Source{Store: chromeStoreV2}, nil
+		}
+
+		return &watch.ChromeSource{Store: chromeStore}, nil
+	case "firefox":
+		firefoxStore, err := getFirefoxStore(c)
+		if err != nil {
+			return nil, err
+		}
+
+		return &watch.FirefoxSource{Store: firefoxStore}, nil
+	default:
+		return nil, fmt.Errorf("status of the extensions in %s isn't available", store)
+	}
+}
+
+// newWatcher returns the watcher of the configured extensions selected by the
+// command arguments.
+func newWatcher(c *cli.Context, cfg *appConfig) (w *watch.Watcher, err error) {
+	names, err := cfg.selectExtensions(c.Args().Slice())
+	if err != nil {
+		return nil, err
+	}
+
+	targets := statusTargets(c, cfg, names)
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("nothing to watch")
+	}
+
+	notifier, err := statusNotifier(c, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := alertRules(c, cfg, names)
+	if err != nil {
+		return nil, err
+	}
+
+	return &watch.Watcher{
+		Targets:   targets,
+		Notifier:  notifier,
+		StatePath: c.String("state"),
+		Interval:  c.Duration("interval"),
+		Rules:     rules,
+	}, nil
+}
+
+// statusTargets returns the targets for the configured extensions selected by
+// the names.  The stores, which sources can't be created, are skipped with a
+// warning.
+func statusTargets(c *cli.Context, cfg *appConfig, names []string) (targets []*watch.Target) {
+	sources := map[string]watch.Source{}
+	sourceErrs := map[string]error{}
+
+	for _, name := range names {
+		for store, appID := range cfg.Extensions[name].storeIDs() {
+			source, ok := sources[store]
+			if !ok && sourceErrs[store] == nil {
+				var err error
+				source, err = newWatchSource(c, store)
+				if err != nil {
+					log.Info("skipping %s: %s", store, err)
+					sourceErrs[store] = err
+
+					continue
+				}
+
+				sources[store] = source
+			}
+
+			if source == nil {
+				continue
+			}
+
+			targets = append(targets, &watch.Target{
+				Extension: name,
+				Store:     store,
+				AppID:     appID,
+				Source:    source,
+			})
+		}
+	}
+
+	return targets
+}
+
+// statusNotifier returns the notifier of the status changes, which prints
+// them, sends them to the configured notifiers and records them to the
+// history.
+func statusNotifier(c *cli.Context, cfg *appConfig) (notifier notify.Multi, err error) {
+	notifiers, err := newNotifiers(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating notifiers: %w", err)
+	}
+
+	notifier = append(notify.Multi{&notify.Writer{W: os.Stdout}}, allNotifiers(notifiers)...)
+	if historyPath := c.String("history"); historyPath != "" {
+		notifier = append(notifier, &history.Notifier{Path: historyPath})
+	}
+
+	return notifier, nil
+}
+
+// watchExtensions polls the status of the configured extensions, prints the
+// changes and sends them to the configured notifiers.
+func watchExtensions(c *cli.Context) (err error) {
+	cfg, err := readConfig(c.String("config"))
+	if err != nil {
+		return err
+	}
+
+	w, err := newWatcher(c, cfg)
+	if err != nil {
+		return err
+	}
+
+	if c.Bool("once") {
+		_, err = w.Check(c.Context)
+
+		return err
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	return w.Run(ctx)
+}
+
+// alertRules returns the alert rules of the extensions selected by the names.
+// The alerts are printed, recorded to the history and sent to the notifiers
+// named in the config.
+func alertRules(c *cli.Context, cfg *appConfig, names []string) (rules map[string]*watch.Rules, err error) {
+	notifiers, err := newNotifiers(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("creating notifiers: %w", err)
+	}
+
+	rules = map[string]*watch.Rules{}
+	for _, name := range names {
+		alerts := cfg.Extensions[name].Alerts
+		if alerts == nil {
+			continue
+		}
+
+		r := &watch.Rules{
+			Review:   time.Duration(alerts.ReviewDays) * 24 * time.Hour,
+			Drift:    time.Duration(alerts.DriftHours) * time.Hour,
+			Disabled: alerts.Disabled,
+		}
+
+		if len(alerts.Notifiers) > 0 {
+			notifier := notify.Multi{&notify.Writer{W: os.Stdout}}
+			if historyPath := c.String("history"); historyPath != "" {
+				notifier = append(notifier, &history.Notifier{Path: historyPath})
+			}
+
+			for _, notifierName := range alerts.Notifiers {
+				n, ok := notifiers[notifierName]
+				if !ok {
+					return nil, fmt.Errorf("extension %q: unknown notifier %q", name, notifierName)
+				}
+
+				notifier = append(notifier, n)
+			}
+
+			r.Notifier = notifier
+		}
+
+		rules[name] = r
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,251 @@
+// Package api contains the HTTP JSON API, which runs the store operations as
+// the background jobs.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/log"
+)
+
+// PathPrefix is the prefix of the API paths.
+const PathPrefix = "/api/v1/"
+
+// DefaultMaxUploadSize is the default maximum size of the uploaded extension.
+const DefaultMaxUploadSize = 256 * 1024 * 1024
+
+// maxJSONSize is the maximum size of the JSON request body.
+const maxJSONSize = 64 * 1024
+
+// API serves the jobs over HTTP.  The requests are authenticated with the
+// bearer token.
+type API struct {
+	Jobs *Jobs
+	// Token must be sent in the Authorization header as "Bearer <token>".
+	Token string
+	// Context is the context the jobs run with, so that they outlive the
+	// request.  context.Background is used if nil.
+	Context context.Context
+	// MaxUploadSize is the maximum size of the uploaded extension,
+	// DefaultMaxUploadSize is used if zero.
+	MaxUploadSize int64
+}
+
+// type check
+var _ http.Handler = (*API)(nil)
+
+// errorResponse is the body of the error response.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeJSON writes the value as the JSON response.
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Debug("api: writing response: %s", err)
+	}
+}
+
+// writeError writes the error response.
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, &errorResponse{Error: err.Error()})
+}
+
+// authorized returns true if the request has the valid token.
+func (a *API) authorized(r *http.Request) (ok bool) {
+	header := r.Header.Get("Authorization")
+	if a.Token == "" || !strings.HasPrefix(header, "Bearer ") {
+		return false
+	}
+
+	token := strings.TrimPrefix(header, "Bearer ")
+
+	return subtle.ConstantTimeCompare([]byte(token), []byte(a.Token)) == 1
+}
+
+// ServeHTTP implements the http.Handler interface for *API.
+func (a *API) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !a.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="extdash"`)
+		writeError(w, http.StatusUnauthorized, fmt.Errorf("invalid or missing token"))
+
+		return
+	}
+
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, PathPrefix), "/")
+	parts := strings.Split(path, "/")
+
+	switch {
+	case path == "jobs":
+		switch r.Method {
+		case http.MethodPost:
+			a.createJob(w, r)
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, a.Jobs.List())
+		default:
+			methodNotAllowed(w, r, http.MethodGet, http.MethodPost)
+		}
+	case len(parts) == 2 && parts[0] == "jobs":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, r, http.MethodGet)
+
+			return
+		}
+
+		job, err := a.Jobs.Get(parts[1])
+		a.writeResult(w, job, err)
+	case len(parts) == 3 && parts[0] == "jobs" && parts[2] == "log":
+		if r.Method != http.MethodGet {
+			methodNotAllowed(w, r, http.MethodGet)
+
+			return
+		}
+
+		entries, err := a.Jobs.Log(parts[1])
+		a.writeResult(w, entries, err)
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("%s not found", r.URL.Path))
+	}
+}
+
+// methodNotAllowed writes the error response listing the allowed methods.
+func methodNotAllowed(w http.ResponseWriter, r *http.Request, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s is not allowed", r.Method))
+}
+
+// writeResult writes the result or the error of the job lookup.
+func (a *API) writeResult(w http.ResponseWriter, result any, err error) {
+	if errors.Is(err, ErrJobNotFound) {
+		writeError(w, http.StatusNotFound, err)
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+	} else {
+		writeJSON(w, http.StatusOK, result)
+	}
+}
+
+// createJob starts the job from the JSON or the multipart form request.  The
+// extension archive is sent as the "file" part of the form.
+func (a *API) createJob(w http.ResponseWriter, r *http.Request) {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	var req *Request
+	var err error
+	if mediaType == "multipart/form-data" {
+		req, err = a.parseForm(w, r)
+	} else {
+		req, err = parseJSON(r)
+	}
+
+	if err != nil {
+		if req != nil && req.File != "" {
+			_ = os.Remove(req.File)
+		}
+
+		writeError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	ctx := a.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	job, err := a.Jobs.Start(ctx, req)
+	if err != nil {
+		if req.File != "" {
+			_ = os.Remove(req.File)
+		}
+
+		writeError(w, http.StatusBadRequest, err)
+
+		return
+	}
+
+	w.Header().Set("Location", PathPrefix+"jobs/"+job.ID)
+	writeJSON(w, http.StatusAccepted, job)
+}
+
+// parseJSON parses the request without the file.
+func parseJSON(r *http.Request) (req *Request, err error) {
+	req = &Request{}
+
+	err = json.NewDecoder(io.LimitReader(r.Body, maxJSONSize)).Decode(req)
+	if err != nil {
+		return nil, fmt.Errorf("decoding request: %w", err)
+	}
+
+	return req, nil
+}
+
+// parseForm parses the request with the file and saves the file to the
+// temporary directory.
+func (a *API) parseForm(w http.ResponseWriter, r *http.Request) (req *Request, err error) {
+	maxSize := a.MaxUploadSize
+	if maxSize == 0 {
+		maxSize = DefaultMaxUploadSize
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxSize)
+
+	err = r.ParseMultipartForm(32 * 1024 * 1024)
+	if err != nil {
+		return nil, fmt.Errorf("parsing form: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, r.MultipartForm.RemoveAll()) }()
+
+	req = &Request{
+		Operation: Operation(r.FormValue("operation")),
+		Store:     r.FormValue("store"),
+		AppID:     r.FormValue("app_id"),
+	}
+
+	file, _, err := r.FormFile("file")
+	if errors.Is(err, http.ErrMissingFile) {
+		return req, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading file: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, file.Close()) }()
+
+	req.File, err = saveUpload(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// saveUpload saves the uploaded file to the temporary file and returns its
+// path.
+func saveUpload(src io.Reader) (path string, err error) {
+	dst, err := os.CreateTemp("", "extdash-*.zip")
+	if err != nil {
+		return "", fmt.Errorf("creating file: %w", err)
+	}
+
+	_, err = io.Copy(dst, src)
+	err = errors.WithDeferred(err, dst.Close())
+	if err != nil {
+		_ = os.Remove(dst.Name())
+
+		return "", fmt.Errorf("saving file: %w", err)
+	}
+
+	return dst.Name(), nil
+}
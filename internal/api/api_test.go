@@ -0,0 +1,284 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maximtop/extdash/internal/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testToken is the token of the test API.
+const testToken = "secret"
+
+// executed is the request received by the test executor.
+type executed struct {
+	req      api.Request
+	file     []byte
+	fileSeen bool
+}
+
+// newTestServer starts the API with the executor, which records the requests
+// and fails for the store "fail".
+func newTestServer(t *testing.T) (srv *httptest.Server, requests chan executed) {
+	t.Helper()
+
+	requests = make(chan executed, 10)
+
+	jobs := &api.Jobs{
+		Executor: func(_ context.Context, req *api.Request, logf api.Logf) (result any, err error) {
+			e := executed{req: *req}
+			if req.File != "" {
+				e.file, err = os.ReadFile(req.File)
+				e.fileSeen = err == nil
+			}
+
+			requests <- e
+
+			logf("running %s in %s", req.Operation, req.Store)
+
+			if req.Store == "fail" {
+				return nil, fmt.Errorf("store failed")
+			}
+
+			return map[string]string{"status": "ok"}, nil
+		},
+	}
+
+	srv = httptest.NewServer(&api.API{Jobs: jobs, Token: testToken})
+	t.Cleanup(srv.Close)
+
+	return srv, requests
+}
+
+// do sends the request with the test token and decodes the JSON response.
+func do(t *testing.T, req *http.Request, result any) (res *http.Response) {
+	t.Helper()
+
+	req.Header.Set("Authorization", "Bearer "+testToken)
+
+	res, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = res.Body.Close() })
+
+	if result != nil {
+		require.NoError(t, json.NewDecoder(res.Body).Decode(result))
+	}
+
+	return res
+}
+
+// awaitJob polls the job until it finishes.
+func awaitJob(t *testing.T, srv *httptest.Server, id string) (job *api.Job) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		req, err := http.NewRequest(http.MethodGet, srv.URL+api.PathPrefix+"jobs/"+id, nil)
+		require.NoError(t, err)
+
+		job = &api.Job{}
+		res := do(t, req, job)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+
+		return job.State == api.JobSucceeded || job.State == api.JobFailed
+	}, 5*time.Second, 10*time.Millisecond)
+
+	return job
+}
+
+func TestAPI_auth(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	testCases := []struct {
+		name   string
+		header string
+	}{{
+		name:   "missing",
+		header: "",
+	}, {
+		name:   "wrong",
+		header: "Bearer other",
+	}, {
+		name:   "not bearer",
+		header: testToken,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodGet, srv.URL+api.PathPrefix+"jobs", nil)
+			require.NoError(t, err)
+
+			if tc.header != "" {
+				req.Header.Set("Authorization", tc.header)
+			}
+
+			res, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer func() { _ = res.Body.Close() }()
+
+			assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+		})
+	}
+}
+
+func TestAPI_jobs(t *testing.T) {
+	srv, requests := newTestServer(t)
+
+	body := strings.NewReader(`{"operation": "publish", "store": "edge", "app_id": "test"}`)
+	req, err := http.NewRequest(http.MethodPost, srv.URL+api.PathPrefix+"jobs", body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	created := &api.Job{}
+	res := do(t, req, created)
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+	assert.Equal(t, api.PathPrefix+"jobs/"+created.ID, res.Header.Get("Location"))
+	assert.Equal(t, api.OperationPublish, created.Operation)
+
+	e := <-requests
+	assert.Equal(t, api.Request{Operation: api.OperationPublish, Store: "edge", AppID: "test"}, e.req)
+
+	job := awaitJob(t, srv, created.ID)
+	assert.Equal(t, api.JobSucceeded, job.State)
+	assert.Equal(t, map[string]any{"status": "ok"}, job.Result)
+
+	req, err = http.NewRequest(http.MethodGet, srv.URL+api.PathPrefix+"jobs/"+created.ID+"/log", nil)
+	require.NoError(t, err)
+
+	var entries []api.LogEntry
+	res = do(t, req, &entries)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "running publish in edge", entries[1].Message)
+	assert.Equal(t, "job succeeded", entries[2].Message)
+
+	req, err = http.NewRequest(http.MethodGet, srv.URL+api.PathPrefix+"jobs", nil)
+	require.NoError(t, err)
+
+	var jobs []api.Job
+	res = do(t, req, &jobs)
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.Len(t, jobs, 1)
+	assert.Equal(t, created.ID, jobs[0].ID)
+}
+
+func TestAPI_upload(t *testing.T) {
+	srv, requests := newTestServer(t)
+
+	b := &bytes.Buffer{}
+	mw := multipart.NewWriter(b)
+	require.NoError(t, mw.WriteField("operation", "upload"))
+	require.NoError(t, mw.WriteField("store", "fail"))
+	require.NoError(t, mw.WriteField("app_id", "test"))
+
+	fw, err := mw.CreateFormFile("file", "extension.zip")
+	require.NoError(t, err)
+
+	_, err = fw.Write([]byte("zip content"))
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+api.PathPrefix+"jobs", b)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	created := &api.Job{}
+	res := do(t, req, created)
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+
+	e := <-requests
+	assert.True(t, e.fileSeen)
+	assert.Equal(t, "zip content", string(e.file))
+
+	job := awaitJob(t, srv, created.ID)
+	assert.Equal(t, api.JobFailed, job.State)
+	assert.Equal(t, "store failed", job.Error)
+
+	// the uploaded file is removed when the job finishes
+	_, err = os.Stat(e.req.File)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestAPI_errors(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		body     string
+		wantCode int
+		wantErr  string
+	}{{
+		name:     "unknown operation",
+		method:   http.MethodPost,
+		path:     "jobs",
+		body:     `{"operation": "delete", "store": "edge", "app_id": "test"}`,
+		wantCode: http.StatusBadRequest,
+		wantErr:  `unknown operation "delete"`,
+	}, {
+		name:     "no file",
+		method:   http.MethodPost,
+		path:     "jobs",
+		body:     `{"operation": "upload", "store": "chrome", "app_id": "test"}`,
+		wantCode: http.StatusBadRequest,
+		wantErr:  "file is required for upload",
+	}, {
+		name:     "no app",
+		method:   http.MethodPost,
+		path:     "jobs",
+		body:     `{"operation": "status", "store": "chrome"}`,
+		wantCode: http.StatusBadRequest,
+		wantErr:  "app_id is required",
+	}, {
+		name:     "no firefox app",
+		method:   http.MethodPost,
+		path:     "jobs",
+		body:     `{"operation": "status", "store": "firefox"}`,
+		wantCode: http.StatusBadRequest,
+		wantErr:  "app_id is required",
+	}, {
+		name:     "unknown job",
+		method:   http.MethodGet,
+		path:     "jobs/unknown",
+		wantCode: http.StatusNotFound,
+		wantErr:  "job not found",
+	}, {
+		name:     "method",
+		method:   http.MethodDelete,
+		path:     "jobs/unknown",
+		wantCode: http.StatusMethodNotAllowed,
+		wantErr:  "method DELETE is not allowed",
+	}, {
+		name:     "unknown path",
+		method:   http.MethodGet,
+		path:     "other",
+		wantCode: http.StatusNotFound,
+		wantErr:  "/api/v1/other not found",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, srv.URL+api.PathPrefix+tc.path, strings.NewReader(tc.body))
+			require.NoError(t, err)
+
+			resp := struct {
+				Error string `json:"error"`
+			}{}
+
+			res := do(t, req, &resp)
+			assert.Equal(t, tc.wantCode, res.StatusCode)
+			assert.Equal(t, tc.wantErr, resp.Error)
+		})
+	}
+}
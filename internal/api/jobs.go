@@ -0,0 +1,308 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/log"
+)
+
+// ErrJobNotFound is returned when the job with the ID doesn't exist.
+const ErrJobNotFound errors.Error = "job not found"
+
+// DefaultMaxRunning is the default number of the jobs running at once.
+const DefaultMaxRunning = 4
+
+// maxFinished is the number of the finished jobs kept in memory, the oldest
+// ones are removed.
+const maxFinished = 100
+
+// Operation is the operation performed by the job.
+type Operation string
+
+// Operations available through the API.
+const (
+	OperationStatus  Operation = "status"
+	OperationUpload  Operation = "upload"
+	OperationPublish Operation = "publish"
+	OperationSign    Operation = "sign"
+)
+
+// needsFile returns true if the operation requires the extension archive.
+func (o Operation) needsFile() (ok bool) {
+	return o == OperationUpload || o == OperationSign
+}
+
+// JobState is the state of the job.
+type JobState string
+
+// Job states.
+const (
+	JobPending   JobState = "pending"
+	JobRunning   JobState = "running"
+	JobSucceeded JobState = "succeeded"
+	JobFailed    JobState = "failed"
+)
+
+// Request describes the operation requested through the API.
+type Request struct {
+	Operation Operation `json:"operation"`
+	Store     string    `json:"store"`
+	AppID     string    `json:"app_id,omitempty"`
+	// File is the path to the uploaded extension archive, it is removed when
+	// the job finishes.
+	File string `json:"-"`
+}
+
+// validate returns an error if the request is incomplete.
+func (r *Request) validate() (err error) {
+	switch r.Operation {
+	case OperationStatus, OperationUpload, OperationPublish, OperationSign:
+		// go on
+	default:
+		return fmt.Errorf("unknown operation %q", r.Operation)
+	}
+
+	if r.Store == "" {
+		return fmt.Errorf("store is required")
+	}
+
+	// firefox takes the ID from the manifest of the uploaded archive
+	if r.AppID == "" && !(r.Store == "firefox" && r.Operation.needsFile()) {
+		return fmt.Errorf("app_id is required")
+	}
+
+	if r.Operation.needsFile() && r.File == "" {
+		return fmt.Errorf("file is required for %s", r.Operation)
+	}
+
+	return nil
+}
+
+// LogEntry is the message logged by the job.
+type LogEntry struct {
+	Time    time.Time `json:"time"`
+	Message string    `json:"message"`
+}
+
+// Job is the operation running in the background.
+type Job struct {
+	ID         string     `json:"id"`
+	Operation  Operation  `json:"operation"`
+	Store      string     `json:"store"`
+	AppID      string     `json:"app_id,omitempty"`
+	State      JobState   `json:"state"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// Result is the response of the store, if the operation succeeded.
+	Result any `json:"result,omitempty"`
+	// Error is the error of the failed operation.
+	Error string `json:"error,omitempty"`
+
+	req *Request
+	log []LogEntry
+}
+
+// Logf is the function the executor logs the progress of the job with.
+type Logf func(format string, args ...any)
+
+// Executor performs the operation of the job.
+type Executor func(ctx context.Context, req *Request, logf Logf) (result any, err error)
+
+// Jobs runs the jobs in the background and keeps their state.
+type Jobs struct {
+	// Executor performs the operations.
+	Executor Executor
+	// MaxRunning is the number of the jobs running at once,
+	// DefaultMaxRunning is used if zero.
+	MaxRunning int
+	// Now returns the current time, time.Now is used if nil.
+	Now func() time.Time
+
+	mu       sync.Mutex
+	jobs     map[string]*Job
+	finished []string
+	sem      chan struct{}
+}
+
+// now returns the current time.
+func (j *Jobs) now() (t time.Time) {
+	if j.Now != nil {
+		return j.Now()
+	}
+
+	return time.Now()
+}
+
+// newJobID returns the random ID of the job.
+func newJobID() (id string, err error) {
+	b := make([]byte, 8)
+
+	_, err = rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("generating id: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// Start validates the request and starts the job in the background.  The job
+// runs until it finishes or ctx is canceled.
+func (j *Jobs) Start(ctx context.Context, req *Request) (job Job, err error) {
+	err = req.validate()
+	if err != nil {
+		return Job{}, err
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return Job{}, err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.jobs == nil {
+		j.jobs = map[string]*Job{}
+
+		maxRunning := j.MaxRunning
+		if maxRunning == 0 {
+			maxRunning = DefaultMaxRunning
+		}
+
+		j.sem = make(chan struct{}, maxRunning)
+	}
+
+	created := &Job{
+		ID:        id,
+		Operation: req.Operation,
+		Store:     req.Store,
+		AppID:     req.AppID,
+		State:     JobPending,
+		CreatedAt: j.now(),
+		req:       req,
+	}
+	j.jobs[id] = created
+
+	go j.run(ctx, created)
+
+	return created.copy(), nil
+}
+
+// run waits for the free slot and runs the job.
+func (j *Jobs) run(ctx context.Context, job *Job) {
+	select {
+	case j.sem <- struct{}{}:
+		defer func() { <-j.sem }()
+	case <-ctx.Done():
+		j.finish(job, nil, ctx.Err())
+
+		return
+	}
+
+	j.mu.Lock()
+	started := j.now()
+	job.State, job.StartedAt = JobRunning, &started
+	job.log = append(job.log, LogEntry{Time: started, Message: "job started"})
+	j.mu.Unlock()
+
+	logf := func(format string, args ...any) {
+		j.mu.Lock()
+		defer j.mu.Unlock()
+
+		job.log = append(job.log, LogEntry{Time: j.now(), Message: fmt.Sprintf(format, args...)})
+	}
+
+	result, err := j.Executor(ctx, job.req, logf)
+	j.finish(job, result, err)
+}
+
+// finish removes the uploaded file and records the outcome of the job.
+func (j *Jobs) finish(job *Job, result any, err error) {
+	if job.req.File != "" {
+		removeErr := os.Remove(job.req.File)
+		if removeErr != nil {
+			log.Debug("api: removing %q: %s", job.req.File, removeErr)
+		}
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	finished := j.now()
+	job.FinishedAt = &finished
+
+	if err != nil {
+		job.State, job.Error = JobFailed, err.Error()
+		job.log = append(job.log, LogEntry{Time: finished, Message: "job failed: " + err.Error()})
+	} else {
+		job.State, job.Result = JobSucceeded, result
+		job.log = append(job.log, LogEntry{Time: finished, Message: "job succeeded"})
+	}
+
+	j.finished = append(j.finished, job.ID)
+	if len(j.finished) > maxFinished {
+		delete(j.jobs, j.finished[0])
+		j.finished = j.finished[1:]
+	}
+}
+
+// Get returns the job by ID.
+func (j *Jobs) Get(id string) (job Job, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	found, ok := j.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+
+	return found.copy(), nil
+}
+
+// Log returns the log of the job by ID.
+func (j *Jobs) Log(id string) (entries []LogEntry, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	found, ok := j.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+
+	return append([]LogEntry{}, found.log...), nil
+}
+
+// List returns the jobs, the newest first.
+func (j *Jobs) List() (jobs []Job) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	jobs = make([]Job, 0, len(j.jobs))
+	for _, job := range j.jobs {
+		jobs = append(jobs, job.copy())
+	}
+
+	sort.Slice(jobs, func(a, b int) bool {
+		return jobs[a].CreatedAt.After(jobs[b].CreatedAt)
+	})
+
+	return jobs
+}
+
+// copy returns the copy of the job without the log, it must be called with
+// the lock held.
+func (job *Job) copy() (c Job) {
+	c = *job
+	c.log = nil
+
+	return c
+}
@@ -2,6 +2,7 @@
 package chrome
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +10,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/AdguardTeam/golibs/errors"
@@ -29,7 +31,11 @@ const maxReadLimit = 10 * fileutil.MB
 // AuthorizeResponse describes the response received from the Chrome Store
 // authorization request.
 type AuthorizeResponse struct {
-	AccessToken string `json:"access_token"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+	TokenType    string `json:"token_type,omitempty"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
 }
 
 // Authorize retrieves access token.
@@ -70,23 +76,53 @@ func (c *Client) Authorize() (accessToken string, err error) {
 
 // Store describes structure of the store.
 type Store struct {
-	Client *Client
+	Client Authorizer
 	URL    *url.URL
+
+	// PollInterval is the interval between the upload state checks,
+	// DefaultPollInterval is used if zero.
+	PollInterval time.Duration
+	// WaitTimeout is the maximum time to wait for the upload to be processed,
+	// DefaultWaitTimeout is used if zero.
+	WaitTimeout time.Duration
 }
 
 // StatusResponse describes status response fields.
 type StatusResponse struct {
-	Kind        string `json:"kind"`
-	ID          string `json:"id"`
-	PublicKey   string `json:"publicKey"`
-	UploadState string `json:"uploadState"`
-	CrxVersion  string `json:"crxVersion"`
+	Kind        string      `json:"kind"`
+	ID          string      `json:"id"`
+	PublicKey   string      `json:"publicKey"`
+	UploadState string      `json:"uploadState"`
+	CrxVersion  string      `json:"crxVersion"`
+	ItemError   []ItemError `json:"itemError,omitempty"`
 }
 
 const requestTimeout = 30 * time.Second
 
+// Projection describes which version of the item is returned by the store.
+type Projection string
+
+const (
+	// ProjectionDraft returns the last uploaded version of the item.
+	ProjectionDraft Projection = "DRAFT"
+	// ProjectionPublished returns the published version of the item.
+	ProjectionPublished Projection = "PUBLISHED"
+)
+
+// ParseProjection parses the projection case-insensitively.
+func ParseProjection(projection string) (result Projection, err error) {
+	switch Projection(strings.ToUpper(projection)) {
+	case ProjectionDraft:
+		return ProjectionDraft, nil
+	case ProjectionPublished:
+		return ProjectionPublished, nil
+	default:
+		return "", fmt.Errorf("unknown projection %q", projection)
+	}
+}
+
 // Status retrieves status of the extension in the store.
-func (s *Store) Status(appID string) (result []byte, err error) {
+func (s *Store) Status(appID string, projection Projection) (result *StatusResponse, err error) {
 	const apiPath = "chromewebstore/v1.1/items"
 	apiURL := s.URL.JoinPath(apiPath, appID).String()
 
@@ -106,7 +142,7 @@ func (s *Store) Status(appID string) (result []byte, err error) {
 
 	req.Header.Add("Authorization", "Bearer "+accessToken)
 	q := req.URL.Query()
-	q.Add("projection", "DRAFT")
+	q.Add("projection", string(projection))
 	req.URL.RawQuery = q.Encode()
 
 	res, err := client.Do(req)
@@ -117,22 +153,61 @@ func (s *Store) Status(appID string) (result []byte, err error) {
 	defer func() { err = errors.WithDeferred(err, res.Body.Close()) }()
 
 	body, err := io.ReadAll(io.LimitReader(res.Body, maxReadLimit))
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
 
 	if res.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("got code %d, body: %q", res.StatusCode, body)
 	}
 
-	return body, nil
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshaling response body: %w", err)
+	}
+
+	return result, nil
+}
+
+// Versions describes the draft and the published versions of the item.
+type Versions struct {
+	Draft     *StatusResponse
+	Published *StatusResponse
+}
+
+// Live returns true if the last uploaded version is published.
+func (v *Versions) Live() (ok bool) {
+	return v.Draft.CrxVersion != "" && v.Draft.CrxVersion == v.Published.CrxVersion
+}
+
+// Versions retrieves both the draft and the published versions of the item.
+func (s *Store) Versions(appID string) (result *Versions, err error) {
+	draft, err := s.Status(appID, ProjectionDraft)
+	if err != nil {
+		return nil, fmt.Errorf("getting draft: %w", err)
+	}
+
+	published, err := s.Status(appID, ProjectionPublished)
+	if err != nil {
+		return nil, fmt.Errorf("getting published: %w", err)
+	}
+
+	return &Versions{
+		Draft:     draft,
+		Published: published,
+	}, nil
 }
 
 // InsertResponse describes structure returned on the insert request.
 type InsertResponse struct {
-	Kind        string `json:"kind"`
-	ID          string `json:"id"`
-	UploadState string `json:"uploadState"`
+	Kind        string      `json:"kind"`
+	ID          string      `json:"id"`
+	UploadState string      `json:"uploadState"`
+	ItemError   []ItemError `json:"itemError,omitempty"`
 }
 
-// Insert uploads a package to create a new store item.
+// Insert uploads a package to create a new store item and waits until the
+// store processes it.
 func (s *Store) Insert(filePath string) (result *InsertResponse, err error) {
 	const apiPath = "upload/chromewebstore/v1.1/items"
 	apiURL := s.URL.JoinPath(apiPath).String()
@@ -174,6 +249,13 @@ func (s *Store) Insert(filePath string) (result *InsertResponse, err error) {
 	err = json.Unmarshal(responseBody, &result)
 	if err != nil {
 		return nil, fmt.Errorf("unmarshaling response body: %w", err)
+	} else if result == nil {
+		return nil, fmt.Errorf("empty response body")
+	}
+
+	result.UploadState, err = s.awaitUpload(result.ID, result.UploadState, result.ItemError)
+	if err != nil {
+		return nil, fmt.Errorf("awaiting upload: %w", err)
 	}
 
 	return result, nil
@@ -181,12 +263,14 @@ func (s *Store) Insert(filePath string) (result *InsertResponse, err error) {
 
 // UpdateResponse describes response returned on update request.
 type UpdateResponse struct {
-	Kind        string `json:"kind"`
-	ID          string `json:"id"`
-	UploadState string `json:"uploadState"`
+	Kind        string      `json:"kind"`
+	ID          string      `json:"id"`
+	UploadState string      `json:"uploadState"`
+	ItemError   []ItemError `json:"itemError,omitempty"`
 }
 
-// Update uploads new version of the package to the store.
+// Update uploads new version of the package to the store and waits until the
+// store processes it.
 func (s *Store) Update(appID, filePath string) (result *UpdateResponse, err error) {
 	const apiPath = "upload/chromewebstore/v1.1/items/"
 	apiURL := s.URL.JoinPath(apiPath, appID).String()
@@ -228,6 +312,13 @@ func (s *Store) Update(appID, filePath string) (result *UpdateResponse, err erro
 	err = json.Unmarshal(responseBody, &result)
 	if err != nil {
 		return nil, fmt.Errorf("unmarshaling response body: %w", err)
+	} else if result == nil {
+		return nil, fmt.Errorf("empty response body")
+	}
+
+	result.UploadState, err = s.awaitUpload(appID, result.UploadState, result.ItemError)
+	if err != nil {
+		return nil, fmt.Errorf("awaiting upload: %w", err)
 	}
 
 	return result, nil
@@ -241,11 +332,62 @@ type PublishResponse struct {
 	StatusDetail []string `json:"statusDetail"`
 }
 
+// PublishTarget describes the audience of the published item.
+type PublishTarget string
+
+const (
+	// PublishTargetDefault publishes the item to everyone.
+	PublishTargetDefault PublishTarget = "default"
+	// PublishTargetTrustedTesters publishes the item to the trusted testers
+	// only.
+	PublishTargetTrustedTesters PublishTarget = "trustedTesters"
+)
+
+// ParsePublishTarget parses the publish target, empty string is parsed as
+// PublishTargetDefault.
+func ParsePublishTarget(target string) (result PublishTarget, err error) {
+	switch PublishTarget(target) {
+	case "", PublishTargetDefault:
+		return PublishTargetDefault, nil
+	case PublishTargetTrustedTesters:
+		return PublishTargetTrustedTesters, nil
+	default:
+		return "", fmt.Errorf("unknown publish target %q", target)
+	}
+}
+
+// PublishOptions represents the options for the publishing.
+type PublishOptions struct {
+	// Target is the audience of the published item, PublishTargetDefault is
+	// used if empty.
+	Target PublishTarget
+	// DeployPercentage is the percentage of the users who receive the
+	// published version, all users receive it if zero.
+	DeployPercentage int
+}
+
+// publishRequest describes body of the publish request.
+type publishRequest struct {
+	DeployPercentage int `json:"deployPercentage"`
+}
+
 // Publish publishes app to the store.
-func (s *Store) Publish(appID string) (result *PublishResponse, err error) {
+func (s *Store) Publish(appID string, publishOptions PublishOptions) (result *PublishResponse, err error) {
 	const apiPath = "chromewebstore/v1.1/items"
 	apiURL := s.URL.JoinPath(apiPath, appID, "publish").String()
 
+	if publishOptions.DeployPercentage < 0 || publishOptions.DeployPercentage > 100 {
+		return nil, fmt.Errorf("deploy percentage must be between 0 and 100, got %d", publishOptions.DeployPercentage)
+	}
+
+	if publishOptions.Target == "" {
+		publishOptions.Target = PublishTargetDefault
+	}
+
+	if publishOptions.Target == PublishTargetTrustedTesters && publishOptions.DeployPercentage != 0 {
+		return nil, fmt.Errorf("deploy percentage can't be used with target %q", publishOptions.Target)
+	}
+
 	accessToken, err := s.Client.Authorize()
 	if err != nil {
 		return nil, fmt.Errorf("getting access token: %w", err)
@@ -253,12 +395,30 @@ func (s *Store) Publish(appID string) (result *PublishResponse, err error) {
 
 	client := &http.Client{Timeout: requestTimeout}
 
-	req, err := http.NewRequest(http.MethodPost, apiURL, nil)
+	var body io.Reader
+	if publishOptions.DeployPercentage != 0 {
+		var requestBody []byte
+		requestBody, err = json.Marshal(publishRequest{DeployPercentage: publishOptions.DeployPercentage})
+		if err != nil {
+			return nil, fmt.Errorf("marshaling request body: %w", err)
+		}
+
+		body = bytes.NewReader(requestBody)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, apiURL, body)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
 	req.Header.Add("Authorization", "Bearer "+accessToken)
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+
+	q := req.URL.Query()
+	q.Add("publishTarget", string(publishOptions.Target))
+	req.URL.RawQuery = q.Encode()
 
 	res, err := client.Do(req)
 	if err != nil {
@@ -279,3 +439,16 @@ func (s *Store) Publish(appID string) (result *PublishResponse, err error) {
 
 	return result, nil
 }
+
+// SetDeployPercentage changes the percentage of the users who receive the
+// already published version.  The store doesn't allow to decrease it.
+func (s *Store) SetDeployPercentage(appID string, percentage int) (result *PublishResponse, err error) {
+	if percentage <= 0 {
+		return nil, fmt.Errorf("deploy percentage must be between 1 and 100, got %d", percentage)
+	}
+
+	return s.Publish(appID, PublishOptions{
+		Target:           PublishTargetDefault,
+		DeployPercentage: percentage,
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/maximtop/extdash/internal/chrome"
 	"github.com/stretchr/testify/assert"
@@ -123,14 +124,10 @@ func TestStatus(t *testing.T) {
 		URL:    storeURL,
 	}
 
-	actualStatusBytes, err := store.Status(appID)
+	actualStatus, err := store.Status(appID, chrome.ProjectionDraft)
 	require.NoError(t, err)
 
-	var actualStatus chrome.StatusResponse
-	err = json.Unmarshal(actualStatusBytes, &actualStatus)
-	require.NoError(t, err)
-
-	assert.Equal(status, actualStatus)
+	assert.Equal(status, *actualStatus)
 }
 
 func TestInsert(t *testing.T) {
@@ -238,6 +235,38 @@ func TestUpdate(t *testing.T) {
 	assert.Equal(updateResponse, *result)
 }
 
+func TestInsertUpdate_nullResponse(t *testing.T) {
+	authServer := createAuthServer(t, accessToken)
+	defer authServer.Close()
+
+	client := chrome.Client{
+		URL:          authServer.URL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RefreshToken: refreshToken,
+	}
+
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte("null"))
+		require.NoError(t, err)
+	}))
+	defer storeServer.Close()
+
+	storeURL, err := url.Parse(storeServer.URL)
+	require.NoError(t, err)
+
+	store := chrome.Store{
+		Client: &client,
+		URL:    storeURL,
+	}
+
+	_, err = store.Insert("testdata/test.txt")
+	assert.EqualError(t, err, "empty response body")
+
+	_, err = store.Update(appID, "testdata/test.txt")
+	assert.EqualError(t, err, "empty response body")
+}
+
 func TestPublish(t *testing.T) {
 	assert := assert.New(t)
 
@@ -263,6 +292,7 @@ func TestPublish(t *testing.T) {
 		assert.Contains(r.URL.Path, "chromewebstore/v1.1/items/"+appID+"/publish")
 		assert.Equal(r.Header.Get("Authorization"), "Bearer "+accessToken)
 		assert.Equal(r.Header.Get("Content-Length"), "0")
+		assert.Equal("default", r.URL.Query().Get("publishTarget"))
 
 		expectedJSON, err := json.Marshal(publishResponse)
 		require.NoError(t, err)
@@ -280,7 +310,267 @@ func TestPublish(t *testing.T) {
 		URL:    storeURL,
 	}
 
-	result, err := store.Publish(appID)
+	result, err := store.Publish(appID, chrome.PublishOptions{})
 	require.NoError(t, err)
 	assert.Equal(publishResponse, *result)
 }
+
+func TestPublish_options(t *testing.T) {
+	testCases := []struct {
+		name           string
+		publishOptions chrome.PublishOptions
+		wantTarget     string
+		wantBody       string
+		wantErr        string
+	}{{
+		name:           "trusted_testers",
+		publishOptions: chrome.PublishOptions{Target: chrome.PublishTargetTrustedTesters},
+		wantTarget:     "trustedTesters",
+		wantBody:       "",
+	}, {
+		name:           "staged_rollout",
+		publishOptions: chrome.PublishOptions{DeployPercentage: 10},
+		wantTarget:     "default",
+		wantBody:       `{"deployPercentage":10}`,
+	}, {
+		name:           "invalid_percentage",
+		publishOptions: chrome.PublishOptions{DeployPercentage: 101},
+		wantErr:        "deploy percentage must be between 0 and 100, got 101",
+	}, {
+		name: "trusted_testers_percentage",
+		publishOptions: chrome.PublishOptions{
+			Target:           chrome.PublishTargetTrustedTesters,
+			DeployPercentage: 10,
+		},
+		wantErr: `deploy percentage can't be used with target "trustedTesters"`,
+	}}
+
+	authServer := createAuthServer(t, accessToken)
+	defer authServer.Close()
+
+	client := chrome.Client{
+		URL:          authServer.URL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RefreshToken: refreshToken,
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, tc.wantTarget, r.URL.Query().Get("publishTarget"))
+
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+
+				assert.Equal(t, tc.wantBody, string(body))
+
+				_, err = w.Write([]byte(`{"item_id": "` + appID + `", "status": ["OK"]}`))
+				require.NoError(t, err)
+			}))
+			defer storeServer.Close()
+
+			storeURL, err := url.Parse(storeServer.URL)
+			require.NoError(t, err)
+
+			store := chrome.Store{
+				Client: &client,
+				URL:    storeURL,
+			}
+
+			result, err := store.Publish(appID, tc.publishOptions)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, []string{"OK"}, result.Status)
+		})
+	}
+}
+
+func TestUpdate_awaitUpload(t *testing.T) {
+	testCases := []struct {
+		name       string
+		itemStates []string
+		wantState  string
+		wantErr    error
+	}{{
+		name: "success",
+		itemStates: []string{
+			`{"id": "` + appID + `", "uploadState": "IN_PROGRESS"}`,
+			`{"id": "` + appID + `", "uploadState": "SUCCESS"}`,
+		},
+		wantState: chrome.UploadStateSuccess,
+		wantErr:   nil,
+	}, {
+		name: "failure",
+		itemStates: []string{
+			`{"id": "` + appID + `", "uploadState": "FAILURE", "itemError": [{
+				"error_code": "PKG_INVALID_VERSION_NUMBER",
+				"error_detail": "Invalid version number in manifest: 1.0.0."
+			}]}`,
+		},
+		wantState: "",
+		wantErr:   chrome.ErrVersionExists,
+	}}
+
+	authServer := createAuthServer(t, accessToken)
+	defer authServer.Close()
+
+	client := chrome.Client{
+		URL:          authServer.URL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RefreshToken: refreshToken,
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			checks := 0
+
+			storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var err error
+
+				switch r.Method {
+				case http.MethodPut:
+					_, err = w.Write([]byte(`{"id": "` + appID + `", "uploadState": "IN_PROGRESS"}`))
+				case http.MethodGet:
+					assert.Equal(t, "DRAFT", r.URL.Query().Get("projection"))
+
+					_, err = w.Write([]byte(tc.itemStates[checks]))
+					checks++
+				default:
+					t.Errorf("unexpected method %s", r.Method)
+				}
+
+				require.NoError(t, err)
+			}))
+			defer storeServer.Close()
+
+			storeURL, err := url.Parse(storeServer.URL)
+			require.NoError(t, err)
+
+			store := chrome.Store{
+				Client:       &client,
+				URL:          storeURL,
+				PollInterval: time.Millisecond,
+				WaitTimeout:  time.Second,
+			}
+
+			result, err := store.Update(appID, "testdata/test.txt")
+			assert.Equal(t, len(tc.itemStates), checks)
+
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+
+				var uploadErr *chrome.UploadError
+				require.ErrorAs(t, err, &uploadErr)
+				assert.Equal(t, chrome.UploadStateFailure, uploadErr.UploadState)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.wantState, result.UploadState)
+		})
+	}
+}
+
+func TestVersions(t *testing.T) {
+	assert := assert.New(t)
+
+	authServer := createAuthServer(t, accessToken)
+	defer authServer.Close()
+
+	client := chrome.Client{
+		URL:          authServer.URL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RefreshToken: refreshToken,
+	}
+
+	crxVersions := map[string]string{
+		"DRAFT":     "1.0.1",
+		"PUBLISHED": "1.0.0",
+	}
+
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		crxVersion, ok := crxVersions[r.URL.Query().Get("projection")]
+		require.True(t, ok)
+
+		err := json.NewEncoder(w).Encode(chrome.StatusResponse{
+			ID:          appID,
+			UploadState: chrome.UploadStateSuccess,
+			CrxVersion:  crxVersion,
+		})
+		require.NoError(t, err)
+	}))
+	defer storeServer.Close()
+
+	storeURL, err := url.Parse(storeServer.URL)
+	require.NoError(t, err)
+
+	store := chrome.Store{
+		Client: &client,
+		URL:    storeURL,
+	}
+
+	versions, err := store.Versions(appID)
+	require.NoError(t, err)
+
+	assert.Equal("1.0.1", versions.Draft.CrxVersion)
+	assert.Equal("1.0.0", versions.Published.CrxVersion)
+	assert.False(versions.Live())
+}
+
+func TestClient_ExchangeCode(t *testing.T) {
+	assert := assert.New(t)
+
+	const (
+		code        = "test_code"
+		redirectURI = "http://127.0.0.1:8080/callback"
+	)
+
+	codeVerifier, err := chrome.NewCodeVerifier()
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(http.MethodPost, r.Method)
+		assert.Equal(clientID, r.FormValue("client_id"))
+		assert.Equal(clientSecret, r.FormValue("client_secret"))
+		assert.Equal(code, r.FormValue("code"))
+		assert.Equal(codeVerifier, r.FormValue("code_verifier"))
+		assert.Equal("authorization_code", r.FormValue("grant_type"))
+		assert.Equal(redirectURI, r.FormValue("redirect_uri"))
+
+		err = json.NewEncoder(w).Encode(map[string]string{
+			"access_token":  accessToken,
+			"refresh_token": refreshToken,
+		})
+		require.NoError(t, err)
+	}))
+	defer server.Close()
+
+	client := chrome.Client{
+		URL:          server.URL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+	}
+
+	consentURL, err := url.Parse(client.AuthCodeURL(chrome.ConsentURL, redirectURI, "test_state", codeVerifier))
+	require.NoError(t, err)
+
+	query := consentURL.Query()
+	assert.Equal(chrome.CodeChallenge(codeVerifier), query.Get("code_challenge"))
+	assert.Equal("S256", query.Get("code_challenge_method"))
+	assert.Equal(redirectURI, query.Get("redirect_uri"))
+	assert.Equal("offline", query.Get("access_type"))
+
+	result, err := client.ExchangeCode(code, codeVerifier, redirectURI)
+	require.NoError(t, err)
+
+	assert.Equal(refreshToken, result.RefreshToken)
+}
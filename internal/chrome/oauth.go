@@ -0,0 +1,137 @@
+package chrome
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// ConsentURL is the address of the Google OAuth consent page.
+const ConsentURL = "https://accounts.google.com/o/oauth2/v2/auth"
+
+// NewCodeVerifier returns a random PKCE code verifier.
+func NewCodeVerifier() (verifier string, err error) {
+	const verifierLen = 32
+
+	buf := make([]byte, verifierLen)
+
+	_, err = rand.Read(buf)
+	if err != nil {
+		return "", fmt.Errorf("reading random bytes: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// CodeChallenge returns the S256 PKCE code challenge for the verifier.
+func CodeChallenge(verifier string) (challenge string) {
+	sum := sha256.Sum256([]byte(verifier))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the address of the consent page, which redirects to
+// redirectURI with the authorization code after the user grants access.
+func (c *Client) AuthCodeURL(consentURL, redirectURI, state, codeVerifier string) (result string) {
+	query := url.Values{
+		"client_id":             {c.ClientID},
+		"redirect_uri":          {redirectURI},
+		"response_type":         {"code"},
+		"scope":                 {Scope},
+		"access_type":           {"offline"},
+		"prompt":                {"consent"},
+		"state":                 {state},
+		"code_challenge":        {CodeChallenge(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	return consentURL + "?" + query.Encode()
+}
+
+// ExchangeCode exchanges the authorization code for the access and the refresh
+// tokens.
+func (c *Client) ExchangeCode(code, codeVerifier, redirectURI string) (result *AuthorizeResponse, err error) {
+	data := url.Values{
+		"client_id":     {c.ClientID},
+		"client_secret": {c.ClientSecret},
+		"code":          {code},
+		"code_verifier": {codeVerifier},
+		"grant_type":    {"authorization_code"},
+		"redirect_uri":  {redirectURI},
+	}
+
+	client := &http.Client{Timeout: requestTimeout}
+
+	res, err := client.PostForm(c.URL, data)
+	if err != nil {
+		return nil, fmt.Errorf("posting a form: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, res.Body.Close()) }()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxReadLimit))
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got code %d, body: %q", res.StatusCode, body)
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshaling response body: %w", err)
+	}
+
+	if result.RefreshToken == "" {
+		return nil, fmt.Errorf("no refresh token in response")
+	}
+
+	return result, nil
+}
+
+// TokenInfoURL is the address of the Google token info endpoint.
+const TokenInfoURL = "https://oauth2.googleapis.com/tokeninfo"
+
+// TokenInfo describes the access token granted to the client.
+type TokenInfo struct {
+	AuthorizedParty string `json:"azp"`
+	Email           string `json:"email"`
+	Scope           string `json:"scope"`
+	ExpiresIn       string `json:"expires_in"`
+}
+
+// GetTokenInfo retrieves info about the access token, e.g. the granted scopes.
+func GetTokenInfo(tokenInfoURL, accessToken string) (result *TokenInfo, err error) {
+	apiURL := tokenInfoURL + "?" + url.Values{"access_token": {accessToken}}.Encode()
+
+	client := &http.Client{Timeout: requestTimeout}
+
+	res, err := client.Get(apiURL)
+	if err != nil {
+		return nil, fmt.Errorf("sending request: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, res.Body.Close()) }()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxReadLimit))
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got code %d, body: %q", res.StatusCode, body)
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshaling response body: %w", err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,158 @@
+package chrome
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Authorizer retrieves access tokens for the store API.
+type Authorizer interface {
+	Authorize() (accessToken string, err error)
+}
+
+// type check
+var _ Authorizer = (*Client)(nil)
+
+// Scope is the OAuth scope required by the store API.
+const Scope = "https://www.googleapis.com/auth/chromewebstore"
+
+// DefaultTokenURI is the token endpoint used if the service account key doesn't
+// specify it.
+const DefaultTokenURI = "https://oauth2.googleapis.com/token"
+
+// ServiceAccountKey describes the required fields of the service account JSON
+// key.
+type ServiceAccountKey struct {
+	ClientEmail  string `json:"client_email"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// ParseServiceAccountKey parses the service account JSON key.  DefaultTokenURI
+// is used if the key has no token_uri.
+func ParseServiceAccountKey(data []byte) (key ServiceAccountKey, err error) {
+	err = json.Unmarshal(data, &key)
+	if err != nil {
+		return ServiceAccountKey{}, fmt.Errorf("unmarshaling service account key: %w", err)
+	}
+
+	if key.ClientEmail == "" || key.PrivateKey == "" {
+		return ServiceAccountKey{}, fmt.Errorf("service account key must contain client_email and private_key")
+	}
+
+	if key.TokenURI == "" {
+		key.TokenURI = DefaultTokenURI
+	}
+
+	return key, nil
+}
+
+// ServiceAccountClient retrieves access tokens by exchanging a JWT assertion
+// signed with the service account key.
+type ServiceAccountClient struct {
+	Key ServiceAccountKey
+	// URL is the token endpoint, Key.TokenURI or DefaultTokenURI is used if
+	// empty.
+	URL string
+	// Now returns the current time, time.Now is used if nil.
+	Now func() time.Time
+}
+
+// type check
+var _ Authorizer = (*ServiceAccountClient)(nil)
+
+// assertion returns the JWT assertion signed with the service account key.
+func (c *ServiceAccountClient) assertion(tokenURL string) (assertion string, err error) {
+	const expiration = time.Hour
+
+	now := time.Now
+	if c.Now != nil {
+		now = c.Now
+	}
+
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(c.Key.PrivateKey))
+	if err != nil {
+		return "", fmt.Errorf("parsing private key: %w", err)
+	}
+
+	issuedAt := now()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   c.Key.ClientEmail,
+		"scope": Scope,
+		"aud":   tokenURL,
+		"iat":   issuedAt.Unix(),
+		"exp":   issuedAt.Add(expiration).Unix(),
+	})
+
+	if c.Key.PrivateKeyID != "" {
+		token.Header["kid"] = c.Key.PrivateKeyID
+	}
+
+	assertion, err = token.SignedString(privateKey)
+	if err != nil {
+		return "", fmt.Errorf("signing token: %w", err)
+	}
+
+	return assertion, nil
+}
+
+// Authorize retrieves access token.
+func (c *ServiceAccountClient) Authorize() (accessToken string, err error) {
+	tokenURL := c.URL
+	if tokenURL == "" {
+		tokenURL = c.Key.TokenURI
+	}
+
+	if tokenURL == "" {
+		tokenURL = DefaultTokenURI
+	}
+
+	assertion, err := c.assertion(tokenURL)
+	if err != nil {
+		return "", fmt.Errorf("creating assertion: %w", err)
+	}
+
+	data := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+
+	client := &http.Client{Timeout: requestTimeout}
+
+	res, err := client.PostForm(tokenURL, data)
+	if err != nil {
+		return "", fmt.Errorf("posting a form: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, res.Body.Close()) }()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxReadLimit))
+	if err != nil {
+		return "", fmt.Errorf("reading response body: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("got code %d, body: %q", res.StatusCode, body)
+	}
+
+	result := &AuthorizeResponse{}
+
+	err = json.Unmarshal(body, result)
+	if err != nil {
+		return "", fmt.Errorf("unmarshaling response body: %w", err)
+	}
+
+	if result.AccessToken == "" {
+		return "", fmt.Errorf("no access token in response: %q", body)
+	}
+
+	return result.AccessToken, nil
+}
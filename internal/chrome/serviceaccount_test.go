@@ -0,0 +1,87 @@
+package chrome_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/maximtop/extdash/internal/chrome"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceAccountClient_Authorize(t *testing.T) {
+	assert := assert.New(t)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	privateKeyPEM := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	})
+
+	const clientEmail = "robot@test-project.iam.gserviceaccount.com"
+
+	now := time.Now()
+
+	var tokenServer *httptest.Server
+	tokenServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(http.MethodPost, r.Method)
+		assert.Equal("urn:ietf:params:oauth:grant-type:jwt-bearer", r.FormValue("grant_type"))
+
+		claims := jwt.MapClaims{}
+		token, err := jwt.ParseWithClaims(r.FormValue("assertion"), claims, func(token *jwt.Token) (any, error) {
+			assert.Equal("test_key_id", token.Header["kid"])
+
+			return &privateKey.PublicKey, nil
+		})
+		require.NoError(t, err)
+		assert.True(token.Valid)
+
+		assert.Equal(clientEmail, claims["iss"])
+		assert.Equal(chrome.Scope, claims["scope"])
+		assert.Equal(tokenServer.URL, claims["aud"])
+		assert.EqualValues(now.Unix(), claims["iat"])
+
+		err = json.NewEncoder(w).Encode(map[string]string{"access_token": accessToken})
+		require.NoError(t, err)
+	}))
+	defer tokenServer.Close()
+
+	keyJSON, err := json.Marshal(map[string]string{
+		"type":           "service_account",
+		"client_email":   clientEmail,
+		"private_key_id": "test_key_id",
+		"private_key":    string(privateKeyPEM),
+		"token_uri":      tokenServer.URL,
+	})
+	require.NoError(t, err)
+
+	key, err := chrome.ParseServiceAccountKey(keyJSON)
+	require.NoError(t, err)
+
+	client := chrome.ServiceAccountClient{
+		Key: key,
+		Now: func() time.Time { return now },
+	}
+
+	result, err := client.Authorize()
+	require.NoError(t, err)
+
+	assert.Equal(accessToken, result)
+}
+
+func TestParseServiceAccountKey_defaultTokenURI(t *testing.T) {
+	key, err := chrome.ParseServiceAccountKey([]byte(`{"client_email": "robot@example.org", "private_key": "key"}`))
+	require.NoError(t, err)
+
+	assert.Equal(t, chrome.DefaultTokenURI, key.TokenURI)
+}
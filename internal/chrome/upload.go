@@ -0,0 +1,171 @@
+package chrome
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/log"
+)
+
+// Upload states returned by the store.
+const (
+	UploadStateSuccess    = "SUCCESS"
+	UploadStateInProgress = "IN_PROGRESS"
+	UploadStateFailure    = "FAILURE"
+	UploadStateNotFound   = "NOT_FOUND"
+)
+
+const (
+	// DefaultPollInterval is the default interval between the upload state
+	// checks.
+	DefaultPollInterval = 5 * time.Second
+	// DefaultWaitTimeout is the default time to wait for the upload to be
+	// processed.
+	DefaultWaitTimeout = 10 * time.Minute
+)
+
+const (
+	// ErrVersionExists is returned when the uploaded version isn't greater
+	// than the version in the store.
+	ErrVersionExists errors.Error = "version already exists"
+	// ErrManifestInvalid is returned when the store can't parse the manifest
+	// of the uploaded package.
+	ErrManifestInvalid errors.Error = "manifest is invalid"
+	// ErrItemNotUpdatable is returned when the item can't be updated, e.g.
+	// because it is in review.
+	ErrItemNotUpdatable errors.Error = "item is not updatable"
+)
+
+// itemErrorKinds maps error codes returned by the store to the errors.
+var itemErrorKinds = map[string]error{
+	"PKG_INVALID_VERSION_NUMBER": ErrVersionExists,
+	"PKG_VERSION_ALREADY_EXISTS": ErrVersionExists,
+	"PKG_MANIFEST_PARSE_ERROR":   ErrManifestInvalid,
+	"PKG_INVALID_MANIFEST":       ErrManifestInvalid,
+	"MANIFEST_INVALID":           ErrManifestInvalid,
+	"ITEM_NOT_UPDATABLE":         ErrItemNotUpdatable,
+}
+
+// ItemError describes an error returned by the store in the itemError field.
+type ItemError struct {
+	Code   string `json:"error_code"`
+	Detail string `json:"error_detail"`
+}
+
+// Error implements the error interface for ItemError.
+func (e ItemError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Detail)
+}
+
+// Is returns true if target is the kind of the error, e.g. ErrVersionExists.
+func (e ItemError) Is(target error) (ok bool) {
+	kind, ok := itemErrorKinds[e.Code]
+
+	return ok && kind == target
+}
+
+// UploadError is returned when the store fails to process the uploaded
+// package.
+type UploadError struct {
+	UploadState string
+	ItemErrors  []ItemError
+}
+
+// Error implements the error interface for *UploadError.
+func (e *UploadError) Error() string {
+	if len(e.ItemErrors) == 0 {
+		return fmt.Sprintf("upload state %s", e.UploadState)
+	}
+
+	itemErrors := make([]string, 0, len(e.ItemErrors))
+	for _, itemErr := range e.ItemErrors {
+		itemErrors = append(itemErrors, itemErr.Error())
+	}
+
+	return fmt.Sprintf("upload state %s: %s", e.UploadState, strings.Join(itemErrors, "; "))
+}
+
+// Is returns true if any of the item errors is target.
+func (e *UploadError) Is(target error) (ok bool) {
+	for _, itemErr := range e.ItemErrors {
+		if itemErr.Is(target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// uploadStatus returns the current upload state and the errors of the item.
+type uploadStatus func() (uploadState string, itemErrors []ItemError, err error)
+
+// pollUpload polls the upload state with status every pollInterval until it
+// leaves the inProgress state, DefaultPollInterval and DefaultWaitTimeout are
+// used if the durations are zero.  It returns the final state and the errors of
+// the item.
+func pollUpload(
+	pollInterval time.Duration,
+	waitTimeout time.Duration,
+	inProgress string,
+	status uploadStatus,
+) (uploadState string, itemErrors []ItemError, err error) {
+	if pollInterval == 0 {
+		pollInterval = DefaultPollInterval
+	}
+
+	if waitTimeout == 0 {
+		waitTimeout = DefaultWaitTimeout
+	}
+
+	startTime := time.Now()
+
+	for uploadState = inProgress; uploadState == inProgress; {
+		if time.Since(startTime) > waitTimeout {
+			return "", nil, fmt.Errorf("upload is still in progress after %s", waitTimeout)
+		}
+
+		log.Debug("upload is in progress, retry in: %s", pollInterval)
+		time.Sleep(pollInterval)
+
+		uploadState, itemErrors, err = status()
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	return uploadState, itemErrors, nil
+}
+
+// awaitUpload polls the item until its upload leaves the IN_PROGRESS state and
+// returns the final state.  It returns *UploadError if the upload has failed.
+func (s *Store) awaitUpload(appID, uploadState string, itemErrors []ItemError) (result string, err error) {
+	if uploadState == UploadStateInProgress {
+		uploadState, itemErrors, err = pollUpload(
+			s.PollInterval,
+			s.WaitTimeout,
+			UploadStateInProgress,
+			func() (state string, errs []ItemError, err error) {
+				item, err := s.Status(appID, ProjectionDraft)
+				if err != nil {
+					return "", nil, fmt.Errorf("getting item: %w", err)
+				}
+
+				return item.UploadState, item.ItemError, nil
+			},
+		)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if uploadState == UploadStateFailure {
+		return "", &UploadError{
+			UploadState: uploadState,
+			ItemErrors:  itemErrors,
+		}
+	}
+
+	return uploadState, nil
+}
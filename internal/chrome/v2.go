@@ -0,0 +1,298 @@
+package chrome
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// Upload states returned by the API v2.
+const (
+	UploadStateV2Succeeded  = "SUCCEEDED"
+	UploadStateV2InProgress = "IN_PROGRESS"
+	UploadStateV2Failed     = "FAILED"
+	UploadStateV2NotFound   = "NOT_FOUND"
+)
+
+// ItemState describes the state of the item revision returned by the API v2.
+type ItemState string
+
+// Item revision states returned by the API v2.
+const (
+	ItemStatePendingReview      ItemState = "PENDING_REVIEW"
+	ItemStateStaged             ItemState = "STAGED"
+	ItemStatePublished          ItemState = "PUBLISHED"
+	ItemStatePublishedToTesters ItemState = "PUBLISHED_TO_TESTERS"
+	ItemStateRejected           ItemState = "REJECTED"
+	ItemStateCancelled          ItemState = "CANCELLED"
+)
+
+// StoreV2 describes structure of the store using the Chrome Web Store API v2.
+type StoreV2 struct {
+	Client Authorizer
+	// URL is the address of the API, e.g.
+	// https://chromewebstore.googleapis.com.
+	URL *url.URL
+	// PublisherID is the ID of the publisher owning the items.
+	PublisherID string
+
+	// PollInterval is the interval between the upload state checks,
+	// DefaultPollInterval is used if zero.
+	PollInterval time.Duration
+	// WaitTimeout is the maximum time to wait for the upload to be processed,
+	// DefaultWaitTimeout is used if zero.
+	WaitTimeout time.Duration
+}
+
+// DistributionChannel describes the version of the item distributed to the
+// users.
+type DistributionChannel struct {
+	DeployPercentage int    `json:"deployPercentage"`
+	CrxVersion       string `json:"crxVersion"`
+}
+
+// ItemRevisionStatus describes the state of the published or the submitted
+// revision of the item.
+type ItemRevisionStatus struct {
+	State                ItemState             `json:"state"`
+	DistributionChannels []DistributionChannel `json:"distributionChannels"`
+}
+
+// CrxVersion returns the version of the revision, or an empty string if the
+// revision has no distribution channels.
+func (s *ItemRevisionStatus) CrxVersion() (version string) {
+	if s == nil || len(s.DistributionChannels) == 0 {
+		return ""
+	}
+
+	return s.DistributionChannels[0].CrxVersion
+}
+
+// StatusResponseV2 describes the response of the fetchStatus request.
+type StatusResponseV2 struct {
+	Name                        string              `json:"name"`
+	ItemID                      string              `json:"itemId"`
+	PublicKey                   string              `json:"publicKey"`
+	PublishedItemRevisionStatus *ItemRevisionStatus `json:"publishedItemRevisionStatus"`
+	SubmittedItemRevisionStatus *ItemRevisionStatus `json:"submittedItemRevisionStatus"`
+	LastAsyncUploadState        string              `json:"lastAsyncUploadState"`
+	TakenDown                   bool                `json:"takenDown"`
+	Warned                      bool                `json:"warned"`
+}
+
+// UploadResponseV2 describes the response of the upload request.
+type UploadResponseV2 struct {
+	Name        string `json:"name"`
+	ItemID      string `json:"itemId"`
+	CrxVersion  string `json:"crxVersion"`
+	UploadState string `json:"uploadState"`
+}
+
+// PublishResponseV2 describes the response of the publish request.
+type PublishResponseV2 struct {
+	Name   string    `json:"name"`
+	ItemID string    `json:"itemId"`
+	State  ItemState `json:"state"`
+}
+
+// deployInfo describes the deploy parameters of the publish request.
+type deployInfo struct {
+	DeployPercentage int `json:"deployPercentage"`
+}
+
+// publishRequestV2 describes body of the publish request.
+type publishRequestV2 struct {
+	PublishType string       `json:"publishType,omitempty"`
+	DeployInfos []deployInfo `json:"deployInfos,omitempty"`
+}
+
+// itemPath returns the API path of the item with the action, e.g.
+// v2/publishers/123/items/abc:publish.
+func (s *StoreV2) itemPath(appID, action string) (apiPath []string) {
+	return []string{"v2/publishers", s.PublisherID, "items", appID + ":" + action}
+}
+
+// do sends the authorized request to the API and decodes the response into
+// result.
+func (s *StoreV2) do(method string, apiPath []string, body io.Reader, contentType string, result any) (err error) {
+	apiURL := s.URL.JoinPath(apiPath...).String()
+
+	accessToken, err := s.Client.Authorize()
+	if err != nil {
+		return fmt.Errorf("getting access token: %w", err)
+	}
+
+	req, err := http.NewRequest(method, apiURL, body)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Add("Authorization", "Bearer "+accessToken)
+	if contentType != "" {
+		req.Header.Add("Content-Type", contentType)
+	}
+
+	client := &http.Client{Timeout: requestTimeout}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, res.Body.Close()) }()
+
+	responseBody, err := io.ReadAll(io.LimitReader(res.Body, maxReadLimit))
+	if err != nil {
+		return fmt.Errorf("reading response body: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("got code %d, body: %q", res.StatusCode, responseBody)
+	}
+
+	err = json.Unmarshal(responseBody, result)
+	if err != nil {
+		return fmt.Errorf("unmarshaling response body: %w", err)
+	}
+
+	return nil
+}
+
+// Status fetches the review status and the published and the submitted
+// revisions of the item.
+func (s *StoreV2) Status(appID string) (result *StatusResponseV2, err error) {
+	err = s.do(http.MethodGet, s.itemPath(appID, "fetchStatus"), nil, "", &result)
+	if err != nil {
+		return nil, fmt.Errorf("fetching status: %w", err)
+	}
+
+	return result, nil
+}
+
+// Upload uploads new version of the package to the store and waits until the
+// store processes it.
+func (s *StoreV2) Upload(appID, filePath string) (result *UploadResponseV2, err error) {
+	file, err := os.Open(filepath.Clean(filePath))
+	if err != nil {
+		return nil, fmt.Errorf("opening file: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, file.Close()) }()
+
+	apiPath := append([]string{"upload"}, s.itemPath(appID, "upload")...)
+
+	// the file is closed above, so that it is closed if the request isn't sent
+	err = s.do(http.MethodPost, apiPath, io.NopCloser(file), "application/zip", &result)
+	if err != nil {
+		return nil, fmt.Errorf("uploading: %w", err)
+	}
+
+	result.UploadState, err = s.awaitUpload(appID, result.UploadState)
+	if err != nil {
+		return nil, fmt.Errorf("awaiting upload: %w", err)
+	}
+
+	return result, nil
+}
+
+// awaitUpload polls the item status until the upload leaves the IN_PROGRESS
+// state and returns the final state.  It returns *UploadError if the upload has
+// failed.
+func (s *StoreV2) awaitUpload(appID, uploadState string) (result string, err error) {
+	if uploadState == UploadStateV2InProgress {
+		uploadState, _, err = pollUpload(
+			s.PollInterval,
+			s.WaitTimeout,
+			UploadStateV2InProgress,
+			func() (state string, errs []ItemError, err error) {
+				status, err := s.Status(appID)
+				if err != nil {
+					return "", nil, fmt.Errorf("getting status: %w", err)
+				}
+
+				return status.LastAsyncUploadState, nil, nil
+			},
+		)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if uploadState == UploadStateV2Failed || uploadState == UploadStateV2NotFound {
+		return "", &UploadError{UploadState: uploadState}
+	}
+
+	return uploadState, nil
+}
+
+// Publish submits the uploaded version of the item for review and publishing.
+// PublishTargetTrustedTesters isn't supported by the API v2, since testers
+// receive the item according to its distribution settings.
+func (s *StoreV2) Publish(appID string, publishOptions PublishOptions) (result *PublishResponseV2, err error) {
+	if publishOptions.DeployPercentage < 0 || publishOptions.DeployPercentage > 100 {
+		return nil, fmt.Errorf("deploy percentage must be between 0 and 100, got %d", publishOptions.DeployPercentage)
+	}
+
+	if publishOptions.Target != "" && publishOptions.Target != PublishTargetDefault {
+		return nil, fmt.Errorf("target %q isn't supported by the API v2", publishOptions.Target)
+	}
+
+	request := publishRequestV2{PublishType: "DEFAULT_PUBLISH"}
+	if publishOptions.DeployPercentage != 0 {
+		request.DeployInfos = []deployInfo{{DeployPercentage: publishOptions.DeployPercentage}}
+	}
+
+	requestBody, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling request body: %w", err)
+	}
+
+	err = s.do(http.MethodPost, s.itemPath(appID, "publish"), bytes.NewReader(requestBody), "application/json", &result)
+	if err != nil {
+		return nil, fmt.Errorf("publishing: %w", err)
+	}
+
+	return result, nil
+}
+
+// CancelSubmission cancels the submission of the item which is pending review.
+func (s *StoreV2) CancelSubmission(appID string) (err error) {
+	var result struct{}
+
+	err = s.do(http.MethodPost, s.itemPath(appID, "cancelSubmission"), nil, "", &result)
+	if err != nil {
+		return fmt.Errorf("cancelling submission: %w", err)
+	}
+
+	return nil
+}
+
+// SetDeployPercentage changes the percentage of the users who receive the
+// published version.  The store doesn't allow to decrease it.
+func (s *StoreV2) SetDeployPercentage(appID string, percentage int) (err error) {
+	if percentage <= 0 || percentage > 100 {
+		return fmt.Errorf("deploy percentage must be between 1 and 100, got %d", percentage)
+	}
+
+	requestBody, err := json.Marshal(deployInfo{DeployPercentage: percentage})
+	if err != nil {
+		return fmt.Errorf("marshaling request body: %w", err)
+	}
+
+	var result struct{}
+
+	apiPath := s.itemPath(appID, "setPublishedDeployPercentage")
+
+	err = s.do(http.MethodPost, apiPath, bytes.NewReader(requestBody), "application/json", &result)
+	if err != nil {
+		return fmt.Errorf("setting deploy percentage: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package chrome_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maximtop/extdash/internal/chrome"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const publisherID = "test_publisher_id"
+
+// storeV2Stub is a stand-in for the Chrome Web Store API v2 keeping the state
+// of a single item.
+type storeV2Stub struct {
+	t *testing.T
+
+	mu               sync.Mutex
+	uploadState      string
+	publishedVersion string
+	submittedVersion string
+	submittedState   chrome.ItemState
+	uploadedVersion  string
+}
+
+// ServeHTTP implements the http.Handler interface for *storeV2Stub.
+func (s *storeV2Stub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	assert.Equal(s.t, "Bearer "+accessToken, r.Header.Get("Authorization"))
+
+	itemPrefix := "/v2/publishers/" + publisherID + "/items/" + appID + ":"
+
+	var response any
+
+	switch r.URL.Path {
+	case "/upload" + itemPrefix + "upload":
+		assert.Equal(s.t, http.MethodPost, r.Method)
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(s.t, err)
+
+		s.uploadedVersion = strings.TrimSpace(string(body))
+		s.uploadState = chrome.UploadStateV2Succeeded
+
+		response = chrome.UploadResponseV2{ItemID: appID, UploadState: chrome.UploadStateV2InProgress}
+	case itemPrefix + "publish":
+		assert.Equal(s.t, http.MethodPost, r.Method)
+
+		var request map[string]any
+		require.NoError(s.t, json.NewDecoder(r.Body).Decode(&request))
+		assert.Equal(s.t, "DEFAULT_PUBLISH", request["publishType"])
+
+		s.submittedVersion = s.uploadedVersion
+		s.submittedState = chrome.ItemStatePendingReview
+
+		response = chrome.PublishResponseV2{ItemID: appID, State: s.submittedState}
+	case itemPrefix + "cancelSubmission":
+		assert.Equal(s.t, http.MethodPost, r.Method)
+
+		s.submittedState = chrome.ItemStateCancelled
+
+		response = struct{}{}
+	case itemPrefix + "fetchStatus":
+		assert.Equal(s.t, http.MethodGet, r.Method)
+
+		status := chrome.StatusResponseV2{
+			ItemID:               appID,
+			LastAsyncUploadState: s.uploadState,
+			PublishedItemRevisionStatus: &chrome.ItemRevisionStatus{
+				State:                chrome.ItemStatePublished,
+				DistributionChannels: []chrome.DistributionChannel{{DeployPercentage: 100, CrxVersion: s.publishedVersion}},
+			},
+		}
+
+		if s.submittedState != "" {
+			status.SubmittedItemRevisionStatus = &chrome.ItemRevisionStatus{
+				State:                s.submittedState,
+				DistributionChannels: []chrome.DistributionChannel{{DeployPercentage: 100, CrxVersion: s.submittedVersion}},
+			}
+		}
+
+		response = status
+	default:
+		http.NotFound(w, r)
+
+		return
+	}
+
+	require.NoError(s.t, json.NewEncoder(w).Encode(response))
+}
+
+func TestStoreV2(t *testing.T) {
+	assert := assert.New(t)
+
+	authServer := createAuthServer(t, accessToken)
+	defer authServer.Close()
+
+	client := chrome.Client{
+		URL:          authServer.URL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RefreshToken: refreshToken,
+	}
+
+	stub := &storeV2Stub{t: t, publishedVersion: "1.0.0"}
+
+	storeServer := httptest.NewServer(stub)
+	defer storeServer.Close()
+
+	storeURL, err := url.Parse(storeServer.URL)
+	require.NoError(t, err)
+
+	store := chrome.StoreV2{
+		Client:       &client,
+		URL:          storeURL,
+		PublisherID:  publisherID,
+		PollInterval: time.Millisecond,
+		WaitTimeout:  time.Second,
+	}
+
+	uploadResponse, err := store.Upload(appID, "testdata/test.txt")
+	require.NoError(t, err)
+	assert.Equal(chrome.UploadStateV2Succeeded, uploadResponse.UploadState)
+
+	publishResponse, err := store.Publish(appID, chrome.PublishOptions{DeployPercentage: 50})
+	require.NoError(t, err)
+	assert.Equal(chrome.ItemStatePendingReview, publishResponse.State)
+
+	status, err := store.Status(appID)
+	require.NoError(t, err)
+	assert.Equal("1.0.0", status.PublishedItemRevisionStatus.CrxVersion())
+	assert.Equal("test file", status.SubmittedItemRevisionStatus.CrxVersion())
+	assert.Equal(chrome.ItemStatePendingReview, status.SubmittedItemRevisionStatus.State)
+
+	err = store.CancelSubmission(appID)
+	require.NoError(t, err)
+
+	status, err = store.Status(appID)
+	require.NoError(t, err)
+	assert.Equal(chrome.ItemStateCancelled, status.SubmittedItemRevisionStatus.State)
+
+	_, err = store.Publish(appID, chrome.PublishOptions{Target: chrome.PublishTargetTrustedTesters})
+	assert.Error(err)
+}
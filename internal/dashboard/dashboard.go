@@ -0,0 +1,288 @@
+// Package dashboard contains the web dashboard of the extensions, which shows
+// their status in the stores, the last releases and the statistics.
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AdguardTeam/golibs/log"
+	"github.com/maximtop/extdash/internal/history"
+	"github.com/maximtop/extdash/internal/metrics"
+	"github.com/maximtop/extdash/internal/stats"
+	"github.com/maximtop/extdash/internal/watch"
+)
+
+// DefaultStatsInterval is the default interval between the statistics
+// collections, the stores update them about once a day.
+const DefaultStatsInterval = 24 * time.Hour
+
+// chartPeriod is the period of the statistics shown on the charts.
+const chartPeriod = 90 * 24 * time.Hour
+
+// maxReleases is the number of the last releases shown for the extension in
+// the store.
+const maxReleases = 5
+
+// Dashboard refreshes the status and the statistics of the extensions on a
+// schedule and serves the page showing them.
+type Dashboard struct {
+	// Extensions contains the IDs of the extensions in the stores by the
+	// extension name.
+	Extensions map[string]map[string]string
+	// Watcher retrieves the status of the extensions and notifies about the
+	// changes, the status isn't shown if nil.
+	Watcher *watch.Watcher
+	// StatsTargets are the extensions to collect the statistics of.
+	StatsTargets []*stats.Target
+	// HistoryPath is the path to the history database, which keeps the
+	// releases and the statistics.  They aren't shown if empty.
+	HistoryPath string
+	// Interval is the interval between the status refreshes,
+	// watch.DefaultInterval is used if zero.
+	Interval time.Duration
+	// StatsInterval is the interval between the statistics collections,
+	// DefaultStatsInterval is used if zero.
+	StatsInterval time.Duration
+	// Errors are the counters of the store API errors exposed with the
+	// metrics, they aren't exposed if nil.
+	Errors *metrics.Errors
+	// Now returns the current time, time.Now is used if nil.
+	Now func() time.Time
+
+	mu   sync.RWMutex
+	page *pageData
+	// statsCollectedAt is the time of the last statistics collection.
+	statsCollectedAt time.Time
+}
+
+// now returns the current time.
+func (d *Dashboard) now() (t time.Time) {
+	if d.Now != nil {
+		return d.Now()
+	}
+
+	return time.Now()
+}
+
+// Refresh retrieves the status of the extensions, collects the statistics if
+// StatsInterval has passed since the last collection, and rebuilds the page.
+// The errors of the stores are logged, so the page shows the last known data.
+func (d *Dashboard) Refresh(ctx context.Context) (err error) {
+	if d.Watcher != nil && len(d.Watcher.Targets) > 0 {
+		_, checkErr := d.Watcher.Check(ctx)
+		if checkErr != nil {
+			log.Error("dashboard: %s", checkErr)
+		}
+	}
+
+	if d.statsCollectedAt.IsZero() {
+		d.statsCollectedAt, err = d.lastStatsTime()
+		if err != nil {
+			return fmt.Errorf("reading history: %w", err)
+		}
+	}
+
+	statsInterval := d.StatsInterval
+	if statsInterval == 0 {
+		statsInterval = DefaultStatsInterval
+	}
+
+	now := d.now()
+	if len(d.StatsTargets) > 0 && now.Sub(d.statsCollectedAt) >= statsInterval {
+		d.collectStats(now)
+	}
+
+	page, err := d.buildPage(now)
+	if err != nil {
+		return fmt.Errorf("building page: %w", err)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.page = page
+
+	return nil
+}
+
+// lastStatsTime returns the time of the last statistics recorded to the
+// history, so that the restarted dashboard doesn't collect them too often.
+func (d *Dashboard) lastStatsTime() (t time.Time, err error) {
+	records, err := d.records(&history.Filter{Kind: history.KindStats})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	for _, r := range records {
+		if r.Time.After(t) {
+			t = r.Time
+		}
+	}
+
+	return t, nil
+}
+
+// collectStats collects the statistics and records them to the history.
+func (d *Dashboard) collectStats(now time.Time) {
+	samples, err := stats.Collect(d.StatsTargets, now)
+	if err != nil {
+		log.Error("dashboard: %s", err)
+	}
+
+	d.statsCollectedAt = now
+
+	if len(samples) == 0 || d.HistoryPath == "" {
+		return
+	}
+
+	records := make([]*history.Record, 0, len(samples))
+	for _, sample := range samples {
+		records = append(records, history.FromSample(sample))
+	}
+
+	err = history.Add(d.HistoryPath, records...)
+	if err != nil {
+		log.Error("dashboard: recording stats: %s", err)
+	}
+}
+
+// records returns the history records selected by the filter, or nil if the
+// history isn't configured.
+func (d *Dashboard) records(filter *history.Filter) (records []*history.Record, err error) {
+	if d.HistoryPath == "" {
+		return nil, nil
+	}
+
+	db, err := history.Open(d.HistoryPath)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		closeErr := db.Close()
+		if closeErr != nil {
+			log.Debug("dashboard: closing history: %s", closeErr)
+		}
+	}()
+
+	return db.Find(filter)
+}
+
+// buildPage returns the page data from the last known status and the history.
+func (d *Dashboard) buildPage(now time.Time) (page *pageData, err error) {
+	records, err := d.records(&history.Filter{Since: now.Add(-chartPeriod)})
+	if err != nil {
+		return nil, err
+	}
+
+	releases := map[string][]*history.Record{}
+	samples := map[string][]*stats.Sample{}
+	for _, r := range records {
+		key := r.Store + "/" + r.AppID
+		switch r.Kind {
+		case history.KindRelease:
+			releases[key] = append(releases[key], r)
+		case history.KindStats:
+			if r.Stats != nil {
+				samples[key] = append(samples[key], r.Stats)
+			}
+		}
+	}
+
+	var snapshots map[string]watch.Snapshot
+	if d.Watcher != nil {
+		snapshots = d.Watcher.Snapshots()
+	}
+
+	page = &pageData{UpdatedAt: now}
+
+	names := make([]string, 0, len(d.Extensions))
+	for name := range d.Extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ext := &extensionView{Name: name}
+
+		for _, store := range sortedStores(d.Extensions[name]) {
+			appID := d.Extensions[name][store]
+			key := store + "/" + appID
+
+			sv := &storeView{
+				Store:    store,
+				AppID:    appID,
+				Releases: lastReleases(releases[key]),
+			}
+
+			if snapshot, ok := snapshots[key]; ok {
+				sv.Snapshot = &snapshot
+			}
+
+			// edge statistics are found by the CRX ID
+			statsKey := key
+			for _, t := range d.StatsTargets {
+				if t.Extension == name && t.Store == store {
+					statsKey = store + "/" + t.AppID
+				}
+			}
+
+			if series := samples[statsKey]; len(series) > 0 {
+				sv.Sample = series[len(series)-1]
+				sv.Chart = newChart(series)
+			}
+
+			ext.Stores = append(ext.Stores, sv)
+		}
+
+		page.Extensions = append(page.Extensions, ext)
+	}
+
+	return page, nil
+}
+
+// sortedStores returns the names of the stores sorted.
+func sortedStores(ids map[string]string) (stores []string) {
+	for store := range ids {
+		stores = append(stores, store)
+	}
+	sort.Strings(stores)
+
+	return stores
+}
+
+// lastReleases returns the last maxReleases records, the newest first.
+func lastReleases(records []*history.Record) (last []*history.Record) {
+	for i := len(records) - 1; i >= 0 && len(last) < maxReleases; i-- {
+		last = append(last, records[i])
+	}
+
+	return last
+}
+
+// Run refreshes the dashboard every Interval until ctx is canceled.
+func (d *Dashboard) Run(ctx context.Context) {
+	interval := d.Interval
+	if interval == 0 {
+		interval = watch.DefaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		err := d.Refresh(ctx)
+		if err != nil {
+			log.Error("dashboard: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package dashboard_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maximtop/extdash/internal/dashboard"
+	"github.com/maximtop/extdash/internal/history"
+	"github.com/maximtop/extdash/internal/metrics"
+	"github.com/maximtop/extdash/internal/stats"
+	"github.com/maximtop/extdash/internal/watch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sourceStub always returns the snapshot.
+type sourceStub struct {
+	snapshot watch.Snapshot
+}
+
+func (s *sourceStub) Snapshot(_ string) (snapshot *watch.Snapshot, err error) {
+	snapshot = &watch.Snapshot{}
+	*snapshot = s.snapshot
+
+	return snapshot, nil
+}
+
+// collectorStub returns the growing number of users.
+type collectorStub struct {
+	calls int
+}
+
+func (c *collectorStub) Collect(appID string) (sample *stats.Sample, err error) {
+	c.calls++
+
+	return &stats.Sample{
+		Store:   "edge",
+		AppID:   appID,
+		Users:   int64(c.calls * 100),
+		Rating:  4.5,
+		Version: "2.0.0",
+	}, nil
+}
+
+// render returns the body of the dashboard page.
+func render(t *testing.T, d *dashboard.Dashboard) (body string) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	return rec.Body.String()
+}
+
+func TestDashboard(t *testing.T) {
+	historyPath := filepath.Join(t.TempDir(), "history.db")
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	err := history.Add(historyPath, &history.Record{
+		Time:      now.Add(-time.Hour),
+		Kind:      history.KindRelease,
+		Store:     "firefox",
+		AppID:     "test@example.org",
+		Operation: "update",
+		Version:   "1.0.1",
+		State:     history.ReleaseSucceeded,
+	})
+	require.NoError(t, err)
+
+	collector := &collectorStub{}
+	d := &dashboard.Dashboard{
+		Extensions: map[string]map[string]string{
+			"test": {"firefox": "test@example.org", "edge": "product"},
+		},
+		Watcher: &watch.Watcher{
+			Targets: []*watch.Target{{
+				Extension: "test",
+				Store:     "firefox",
+				AppID:     "test@example.org",
+				Source: &sourceStub{snapshot: watch.Snapshot{
+					State:          watch.StateInReview,
+					Version:        "1.0.0",
+					PendingVersion: "1.0.1",
+				}},
+			}},
+			Now: func() time.Time { return now },
+		},
+		StatsTargets: []*stats.Target{{
+			Extension: "test",
+			Store:     "edge",
+			AppID:     "crx",
+			Collector: collector,
+		}},
+		HistoryPath:   historyPath,
+		StatsInterval: 24 * time.Hour,
+		Now:           func() time.Time { return now },
+	}
+
+	assert.Contains(t, render(t, d), "is loading")
+
+	require.NoError(t, d.Refresh(context.Background()))
+
+	body := render(t, d)
+	assert.Contains(t, body, "<h2>test</h2>")
+	assert.Contains(t, body, "state-in_review")
+	assert.Contains(t, body, "pending 1.0.1")
+	assert.Contains(t, body, "update 1.0.1 succeeded")
+	// edge version is taken from the listing
+	assert.Contains(t, body, "2.0.0")
+	// one sample isn't enough for the chart
+	assert.NotContains(t, body, "<polyline")
+
+	// the stats aren't collected again within the interval
+	require.NoError(t, d.Refresh(context.Background()))
+	assert.Equal(t, 1, collector.calls)
+
+	now = now.Add(24 * time.Hour)
+	require.NoError(t, d.Refresh(context.Background()))
+	assert.Equal(t, 2, collector.calls)
+
+	body = render(t, d)
+	assert.Contains(t, body, `<polyline points="0.0,60.0 300.0,0.0"/>`)
+	assert.Contains(t, body, "100&ndash;200")
+}
+
+func TestDashboard_ServeHTTP_notFound(t *testing.T) {
+	d := &dashboard.Dashboard{}
+
+	rec := httptest.NewRecorder()
+	d.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/other", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDashboard_ServeMetrics(t *testing.T) {
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	errs := &metrics.Errors{}
+	errs.Inc("firefox", metrics.EndpointStatus)
+
+	d := &dashboard.Dashboard{
+		Extensions: map[string]map[string]string{
+			"test": {"firefox": "test@example.org"},
+		},
+		Watcher: &watch.Watcher{
+			Targets: []*watch.Target{{
+				Extension: "test",
+				Store:     "firefox",
+				AppID:     "test@example.org",
+				Source: &sourceStub{snapshot: watch.Snapshot{
+					State:          watch.StateInReview,
+					Version:        "1.0.0",
+					PendingVersion: "1.0.1",
+				}},
+			}},
+			Now: func() time.Time { return now },
+		},
+		Errors: errs,
+		Now:    func() time.Time { return now },
+	}
+
+	require.NoError(t, d.Refresh(context.Background()))
+
+	now = now.Add(time.Hour)
+
+	rec := httptest.NewRecorder()
+	d.ServeMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, metrics.ContentType, rec.Header().Get("Content-Type"))
+
+	labels := `extension="test",store="firefox",app_id="test@example.org"`
+	body := rec.Body.String()
+	assert.Contains(t, body, `extdash_extension_info{`+labels+`,state="in_review",version="1.0.0",pending_version="1.0.1"} 1`)
+	assert.Contains(t, body, `extdash_review_duration_seconds{`+labels+`} 3600`)
+	assert.Contains(t, body, `extdash_last_check_timestamp_seconds{`+labels+`} 1.6409952e+09`)
+	assert.Contains(t, body, `extdash_store_api_errors_total{store="firefox",endpoint="status"} 1`)
+	assert.NotContains(t, body, "extdash_users")
+}
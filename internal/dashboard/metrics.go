@@ -0,0 +1,101 @@
+package dashboard
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/AdguardTeam/golibs/log"
+	"github.com/maximtop/extdash/internal/metrics"
+	"github.com/maximtop/extdash/internal/watch"
+)
+
+// families returns the metrics of the extensions from the page built by the
+// last refresh and the error counters.
+func (d *Dashboard) families(page *pageData, now time.Time) (families []*metrics.Family) {
+	info := &metrics.Family{
+		Name: "extdash_extension_info",
+		Help: "State and versions of the extension in the store.",
+		Type: metrics.TypeGauge,
+	}
+	review := &metrics.Family{
+		Name: "extdash_review_duration_seconds",
+		Help: "Time the pending version has been in review, zero if it isn't.",
+		Type: metrics.TypeGauge,
+	}
+	checked := &metrics.Family{
+		Name: "extdash_last_check_timestamp_seconds",
+		Help: "Time of the last successful status check.",
+		Type: metrics.TypeGauge,
+	}
+	users := &metrics.Family{
+		Name: "extdash_users",
+		Help: "Number of the users reported by the store, average daily users for Firefox.",
+		Type: metrics.TypeGauge,
+	}
+	rating := &metrics.Family{
+		Name: "extdash_rating",
+		Help: "Average rating of the extension in the store.",
+		Type: metrics.TypeGauge,
+	}
+
+	if page != nil {
+		for _, ext := range page.Extensions {
+			for _, sv := range ext.Stores {
+				labels := []string{"extension", ext.Name, "store", sv.Store, "app_id", sv.AppID}
+
+				if s := sv.Snapshot; s != nil {
+					info.Add(1, append(
+						labels,
+						"state", string(s.State),
+						"version", sv.Version(),
+						"pending_version", s.PendingVersion,
+					)...)
+
+					inReview := 0.0
+					if s.State == watch.StateInReview {
+						inReview = now.Sub(s.ChangedAt).Seconds()
+					}
+
+					review.Add(inReview, labels...)
+					checked.Add(float64(s.CheckedAt.Unix()), labels...)
+				} else if sv.Sample != nil {
+					info.Add(1, append(labels, "state", "", "version", sv.Version(), "pending_version", "")...)
+				}
+
+				if sv.Sample != nil {
+					users.Add(float64(sv.Sample.Users), labels...)
+					rating.Add(sv.Sample.Rating, labels...)
+				}
+			}
+		}
+	}
+
+	families = []*metrics.Family{info, review, checked, users, rating}
+	if d.Errors != nil {
+		families = append(families, d.Errors.Family())
+	}
+
+	return families
+}
+
+// ServeMetrics serves the metrics of the extensions in the Prometheus text
+// exposition format.
+func (d *Dashboard) ServeMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	d.mu.RLock()
+	page := d.page
+	d.mu.RUnlock()
+
+	w.Header().Set("Content-Type", metrics.ContentType)
+
+	err := metrics.Write(w, d.families(page, d.now())...)
+	if err != nil {
+		log.Debug("dashboard: writing metrics: %s", err)
+	}
+}
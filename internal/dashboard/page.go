@@ -0,0 +1,165 @@
+package dashboard
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AdguardTeam/golibs/log"
+	"github.com/maximtop/extdash/internal/history"
+	"github.com/maximtop/extdash/internal/stats"
+	"github.com/maximtop/extdash/internal/watch"
+)
+
+// Size of the chart in the SVG units.
+const (
+	chartWidth  = 300
+	chartHeight = 60
+)
+
+//go:embed templates/index.html
+var templatesFS embed.FS
+
+// pageTemplate is the template of the dashboard page.
+var pageTemplate = template.Must(template.New("index.html").Funcs(template.FuncMap{
+	"formatTime": func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+
+		return t.Local().Format("2006-01-02 15:04")
+	},
+}).ParseFS(templatesFS, "templates/index.html"))
+
+// pageData is the data of the dashboard page.
+type pageData struct {
+	UpdatedAt  time.Time
+	Extensions []*extensionView
+}
+
+// extensionView is the extension on the dashboard page.
+type extensionView struct {
+	Name   string
+	Stores []*storeView
+}
+
+// storeView is the extension in the store on the dashboard page.
+type storeView struct {
+	Store string
+	AppID string
+	// Snapshot is the last known status, nil if the status isn't available.
+	Snapshot *watch.Snapshot
+	// Sample is the last statistics sample, nil if there is none.
+	Sample   *stats.Sample
+	Releases []*history.Record
+	// Chart is the chart of the users, nil if there are not enough samples.
+	Chart *chart
+}
+
+// Version returns the published version from the status or from the listing.
+func (v *storeView) Version() (version string) {
+	if v.Snapshot != nil && v.Snapshot.Version != "" {
+		return v.Snapshot.Version
+	}
+
+	if v.Sample != nil {
+		return v.Sample.Version
+	}
+
+	return ""
+}
+
+// chart is the line chart of the users drawn with SVG.
+type chart struct {
+	// Points are the points of the SVG polyline.
+	Points string
+	Min    int64
+	Max    int64
+	From   time.Time
+	To     time.Time
+}
+
+// newChart returns the chart of the users in the samples sorted by time, or
+// nil if there are less than two samples.
+func newChart(samples []*stats.Sample) (c *chart) {
+	if len(samples) < 2 {
+		return nil
+	}
+
+	c = &chart{
+		Min:  samples[0].Users,
+		Max:  samples[0].Users,
+		From: samples[0].Time,
+		To:   samples[len(samples)-1].Time,
+	}
+
+	for _, s := range samples {
+		if s.Users < c.Min {
+			c.Min = s.Users
+		}
+
+		if s.Users > c.Max {
+			c.Max = s.Users
+		}
+	}
+
+	period := c.To.Sub(c.From)
+
+	points := make([]string, 0, len(samples))
+	for _, s := range samples {
+		x := 0.0
+		if period > 0 {
+			x = float64(s.Time.Sub(c.From)) / float64(period) * chartWidth
+		}
+
+		// the flat line is drawn in the middle
+		y := chartHeight / 2.0
+		if c.Max > c.Min {
+			y = chartHeight - float64(s.Users-c.Min)/float64(c.Max-c.Min)*chartHeight
+		}
+
+		points = append(points, fmt.Sprintf("%.1f,%.1f", x, y))
+	}
+
+	c.Points = strings.Join(points, " ")
+
+	return c
+}
+
+// ServeHTTP implements the http.Handler interface for *Dashboard.  It serves
+// the dashboard page built by the last refresh.
+func (d *Dashboard) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+
+		return
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+
+		return
+	}
+
+	d.mu.RLock()
+	page := d.page
+	d.mu.RUnlock()
+
+	b := &bytes.Buffer{}
+
+	err := pageTemplate.Execute(b, page)
+	if err != nil {
+		log.Error("dashboard: rendering page: %s", err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, _ = w.Write(b.Bytes())
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/AdguardTeam/golibs/errors"
 	"github.com/AdguardTeam/golibs/log"
+	"github.com/golang-jwt/jwt/v4"
 )
 
 const requestTimeout = 30 * time.Second
@@ -76,6 +77,10 @@ func (c *Client) Authorize() (accessToken string, err error) {
 		return "", fmt.Errorf("reading response: %w", err)
 	}
 
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("got code %d, body: %q", res.StatusCode, responseBody)
+	}
+
 	var authorizeResponse AuthorizeResponse
 
 	err = json.Unmarshal(responseBody, &authorizeResponse)
@@ -83,9 +88,35 @@ func (c *Client) Authorize() (accessToken string, err error) {
 		return "", fmt.Errorf("can't unmarshal response: %s, error: %w", responseBody, err)
 	}
 
+	if authorizeResponse.AccessToken == "" {
+		return "", fmt.Errorf("no access token in response: %s", responseBody)
+	}
+
 	return authorizeResponse.AccessToken, nil
 }
 
+// TokenClaims describes the claims of the access token issued to the client.
+type TokenClaims struct {
+	AppID    string   `json:"appid"`
+	TenantID string   `json:"tid"`
+	Roles    []string `json:"roles"`
+	Scope    string   `json:"scp"`
+	jwt.RegisteredClaims
+}
+
+// ParseTokenClaims returns the claims of the access token.  The signature
+// isn't verified, so the claims must only be used for diagnostics.
+func ParseTokenClaims(accessToken string) (claims *TokenClaims, err error) {
+	claims = &TokenClaims{}
+
+	_, _, err = jwt.NewParser().ParseUnverified(accessToken, claims)
+	if err != nil {
+		return nil, fmt.Errorf("parsing access token: %w", err)
+	}
+
+	return claims, nil
+}
+
 // Store represents the edge store instance
 type Store struct {
 	Client *Client
@@ -11,6 +11,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/maximtop/extdash/internal/edge"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -73,6 +74,61 @@ func TestAuthorize(t *testing.T) {
 	assert.Equal(accessToken, actualAccessToken)
 }
 
+func TestAuthorize_noToken(t *testing.T) {
+	testCases := []struct {
+		name    string
+		code    int
+		body    string
+		wantErr string
+	}{{
+		name:    "empty_token",
+		code:    http.StatusOK,
+		body:    `{"token_type": "Bearer"}`,
+		wantErr: `no access token in response: {"token_type": "Bearer"}`,
+	}, {
+		name:    "error_code",
+		code:    http.StatusUnauthorized,
+		body:    `{"error": "invalid_client"}`,
+		wantErr: `got code 401, body: "{\"error\": \"invalid_client\"}"`,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(tc.code)
+
+				_, err := w.Write([]byte(tc.body))
+				require.NoError(t, err)
+			}))
+			defer authServer.Close()
+
+			client, err := edge.NewClient(clientID, clientSecret, authServer.URL)
+			require.NoError(t, err)
+
+			_, err = client.Authorize()
+			assert.EqualError(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestParseTokenClaims(t *testing.T) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"appid": clientID,
+		"tid":   "test_tenant_id",
+		"roles": []string{"Products.ReadWrite"},
+	})
+
+	signedToken, err := token.SignedString([]byte("test_key"))
+	require.NoError(t, err)
+
+	claims, err := edge.ParseTokenClaims(signedToken)
+	require.NoError(t, err)
+
+	assert.Equal(t, clientID, claims.AppID)
+	assert.Equal(t, "test_tenant_id", claims.TenantID)
+	assert.Equal(t, []string{"Products.ReadWrite"}, claims.Roles)
+}
+
 func TestUploadUpdate(t *testing.T) {
 	assert := assert.New(t)
 
@@ -0,0 +1,153 @@
+package fileutil
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/log"
+)
+
+// excludedDirs contains names of directories which are never added to the
+// source archive, even if they are not ignored by git.
+var excludedDirs = []string{"node_modules", ".git"}
+
+// gitFiles returns paths of the files in the git working tree at dir, which
+// are tracked or untracked but not ignored.  Paths are relative to dir and use
+// forward slashes.
+func gitFiles(dir string) (files []string, err error) {
+	var stderr bytes.Buffer
+
+	// #nosec G204 -- dir is passed as a separate argument.
+	cmd := exec.Command("git", "-C", dir, "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("listing files with git: %w, stderr: %q", err, stderr.String())
+	}
+
+	for _, file := range strings.Split(string(output), "\x00") {
+		if file != "" && !isExcluded(file) {
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+// isExcluded returns true if the file is in one of the excludedDirs.
+func isExcluded(file string) (ok bool) {
+	for _, part := range strings.Split(path.Dir(file), "/") {
+		for _, dir := range excludedDirs {
+			if part == dir {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// addFileToZip writes the file to the zip archive under the name.
+func addFileToZip(writer *zip.Writer, filePath, name string) (err error) {
+	file, err := os.Open(filepath.Clean(filePath))
+	if err != nil {
+		return fmt.Errorf("opening file: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, file.Close()) }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("getting file info: %w", err)
+	}
+
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return fmt.Errorf("creating zip header: %w", err)
+	}
+
+	header.Name = name
+	header.Method = zip.Deflate
+
+	part, err := writer.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("creating zip entry: %w", err)
+	}
+
+	_, err = io.Copy(part, file)
+	if err != nil {
+		return fmt.Errorf("copying file: %w", err)
+	}
+
+	return nil
+}
+
+// ZipGitWorkTree creates zip archive at dst with the files of the git working
+// tree at dir.  Files ignored by git and files in node_modules are skipped.
+// extraFiles, e.g. build instructions, are added to the root of the archive
+// even if they are ignored or are located outside the working tree.
+func ZipGitWorkTree(dir, dst string, extraFiles ...string) (err error) {
+	files, err := gitFiles(dir)
+	if err != nil {
+		return fmt.Errorf("getting files of %q: %w", dir, err)
+	}
+
+	archive, err := os.Create(filepath.Clean(dst))
+	if err != nil {
+		return fmt.Errorf("creating archive: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, archive.Close()) }()
+
+	writer := zip.NewWriter(archive)
+	defer func() { err = errors.WithDeferred(err, writer.Close()) }()
+
+	added := map[string]bool{}
+
+	for _, extraFile := range extraFiles {
+		name := filepath.Base(extraFile)
+
+		err = addFileToZip(writer, extraFile, name)
+		if err != nil {
+			return fmt.Errorf("adding %q: %w", extraFile, err)
+		}
+
+		added[name] = true
+	}
+
+	for _, file := range files {
+		if added[file] {
+			continue
+		}
+
+		filePath := filepath.Join(dir, filepath.FromSlash(file))
+
+		info, err := os.Lstat(filePath)
+		if errors.Is(err, os.ErrNotExist) {
+			// file is deleted from the working tree, but not from the index
+			continue
+		} else if err != nil {
+			return fmt.Errorf("getting info of %q: %w", file, err)
+		}
+
+		if !info.Mode().IsRegular() {
+			log.Debug("skipping %q, not a regular file", file)
+
+			continue
+		}
+
+		err = addFileToZip(writer, filePath, file)
+		if err != nil {
+			return fmt.Errorf("adding %q: %w", file, err)
+		}
+	}
+
+	return nil
+}
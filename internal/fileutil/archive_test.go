@@ -0,0 +1,67 @@
+package fileutil_test
+
+import (
+	"archive/zip"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/maximtop/extdash/internal/fileutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestZipGitWorkTree(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir := t.TempDir()
+
+	files := map[string]string{
+		".gitignore":                "build/\n",
+		"src/index.js":              "console.log('test');",
+		"build/extension.js":        "built",
+		"node_modules/lib/index.js": "dependency",
+		"untracked.txt":             "untracked",
+		"packages/a/node_modules/b": "nested dependency",
+	}
+
+	for name, content := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0o755))
+		require.NoError(t, os.WriteFile(filePath, []byte(content), 0o600))
+	}
+
+	err := exec.Command("git", "-C", dir, "init", "-q").Run()
+	require.NoError(t, err)
+
+	err = exec.Command("git", "-C", dir, "add", ".gitignore", "src").Run()
+	require.NoError(t, err)
+
+	instructions := filepath.Join(t.TempDir(), "BUILD.md")
+	require.NoError(t, os.WriteFile(instructions, []byte("run make"), 0o600))
+
+	dst := filepath.Join(t.TempDir(), "source.zip")
+
+	err = fileutil.ZipGitWorkTree(dir, dst, instructions)
+	require.NoError(t, err)
+
+	reader, err := zip.OpenReader(dst)
+	require.NoError(t, err)
+	defer func() { assert.NoError(t, reader.Close()) }()
+
+	var names []string
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+	}
+	sort.Strings(names)
+
+	assert.Equal(t, []string{".gitignore", "BUILD.md", "src/index.js", "untracked.txt"}, names)
+
+	content, err := fileutil.ReadFileFromZip(dst, "BUILD.md")
+	require.NoError(t, err)
+	assert.Equal(t, "run make", string(content))
+}
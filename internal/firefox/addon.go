@@ -0,0 +1,153 @@
+package firefox
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// Add-on statuses returned by the store api.
+const (
+	AddonStatusPublic   = "public"
+	AddonStatusDisabled = "disabled"
+	AddonStatusDeleted  = "deleted"
+)
+
+// Version file statuses returned by the store api.
+const (
+	FileStatusPublic     = "public"
+	FileStatusUnreviewed = "unreviewed"
+	FileStatusDisabled   = "disabled"
+)
+
+// AddonFile describes the file of the add-on version.
+type AddonFile struct {
+	ID     int    `json:"id"`
+	Status string `json:"status"`
+}
+
+// AddonVersion describes the version of the add-on.
+type AddonVersion struct {
+	ID      int        `json:"id"`
+	Version string     `json:"version"`
+	Channel string     `json:"channel"`
+	File    *AddonFile `json:"file"`
+}
+
+// FileStatus returns the status of the version file, or an empty string if
+// the version has no file.
+func (v *AddonVersion) FileStatus() (status string) {
+	if v == nil || v.File == nil {
+		return ""
+	}
+
+	return v.File.Status
+}
+
+// AddonRatings describes the ratings of the add-on.
+type AddonRatings struct {
+	Average         float64 `json:"average"`
+	BayesianAverage float64 `json:"bayesian_average"`
+	Count           int     `json:"count"`
+	TextCount       int     `json:"text_count"`
+}
+
+// Addon describes the add-on details returned by the store api.
+type Addon struct {
+	ID                int           `json:"id"`
+	GUID              string        `json:"guid"`
+	Slug              string        `json:"slug"`
+	Status            string        `json:"status"`
+	IsDisabled        bool          `json:"is_disabled"`
+	CurrentVersion    *AddonVersion `json:"current_version"`
+	AverageDailyUsers int           `json:"average_daily_users"`
+	WeeklyDownloads   int           `json:"weekly_downloads"`
+	Ratings           AddonRatings  `json:"ratings"`
+}
+
+// Disabled returns true if the add-on is disabled by the developer or by the
+// store, or deleted.
+func (a *Addon) Disabled() (ok bool) {
+	return a.IsDisabled || a.Status == AddonStatusDisabled || a.Status == AddonStatusDeleted
+}
+
+// getJSON sends the authorized GET request to the store api and decodes the
+// response into result.
+func (s *Store) getJSON(apiURL string, result any) (err error) {
+	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	authHeader, err := s.Client.GenAuthHeader()
+	if err != nil {
+		return fmt.Errorf("generating auth header: %w", err)
+	}
+
+	req.Header.Add("Authorization", authHeader)
+
+	res, err := s.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, res.Body.Close()) }()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxReadLimit))
+	if err != nil {
+		return fmt.Errorf("reading response body: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("got code %d, body: %q", res.StatusCode, body)
+	}
+
+	err = json.Unmarshal(body, result)
+	if err != nil {
+		return fmt.Errorf("unmarshalling response body: %s, error: %w", body, err)
+	}
+
+	return nil
+}
+
+// Addon retrieves the details of the add-on, including the current public
+// version and the usage statistics.
+func (s *Store) Addon(appID string) (result *Addon, err error) {
+	apiURL := s.URL.JoinPath("api/v5/addons/addon/", appID, "/").String()
+
+	err = s.getJSON(apiURL, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// LatestListedVersion retrieves the most recently uploaded listed version of
+// the add-on, which may be awaiting review.  It returns ErrVersionNotFound if
+// the add-on has no listed versions.
+func (s *Store) LatestListedVersion(appID string) (result *AddonVersion, err error) {
+	query := url.Values{}
+	query.Add("filter", "all_without_unlisted")
+	query.Add("page_size", "1")
+
+	apiURL := s.URL.JoinPath("api/v5/addons/addon/", appID, "versions/").String() + "?" + query.Encode()
+
+	var versions struct {
+		Results []*AddonVersion `json:"results"`
+	}
+
+	err = s.getJSON(apiURL, &versions)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(versions.Results) == 0 {
+		return nil, ErrVersionNotFound
+	}
+
+	return versions.Results[0], nil
+}
@@ -23,7 +23,7 @@ import (
 
 // AMO main url is https://addons.mozilla.org/
 // Please use AMO dev environment at https://addons-dev.allizom.org/ or the AMO stage
-// environment at https://addons.allizom.org/ for testing.
+// environment at https://addons.allizom.org/ for testing, see EnvironmentURL.
 // credential keys can't be sent via email, so you need to ask them in the chat https://matrix.to/#/#amo:mozilla.org
 // last time I've asked them from mat https://matrix.to/#/@mat:mozilla.org
 // signed xpi build from dev environments is corrupted, so you need to build it from the production environment
@@ -42,8 +42,69 @@ type ClientConfig struct {
 	Now          func() int64
 }
 
-// TODO(maximtop): consider to make this constant an option.
-const requestTimeout = 20 * time.Minute
+const (
+	// DefaultRequestTimeout is the default timeout of a single request to the
+	// store api.
+	DefaultRequestTimeout = 20 * time.Minute
+	// DefaultPollInterval is the default initial interval between the status
+	// checks.
+	DefaultPollInterval = time.Second
+	// DefaultMaxPollInterval is the default upper limit of the interval between
+	// the status checks.
+	DefaultMaxPollInterval = time.Minute
+	// DefaultWaitTimeout is the default time to wait for the extension to be
+	// validated or signed.
+	DefaultWaitTimeout = 20 * time.Minute
+	// DefaultReviewPollInterval is the default interval between the status
+	// checks while the extension awaits a human review.
+	DefaultReviewPollInterval = 30 * time.Minute
+	// DefaultReviewWaitTimeout is the default time to wait for a human review.
+	DefaultReviewWaitTimeout = 7 * 24 * time.Hour
+)
+
+// Environment is the name of the AMO environment.
+type Environment string
+
+// AMO environments.
+const (
+	EnvironmentProduction Environment = "prod"
+	EnvironmentStage      Environment = "stage"
+	EnvironmentDev        Environment = "dev"
+)
+
+// environmentHosts contains hosts of the AMO environments.
+var environmentHosts = map[Environment]string{
+	EnvironmentProduction: "addons.mozilla.org",
+	EnvironmentStage:      "addons.allizom.org",
+	EnvironmentDev:        "addons-dev.allizom.org",
+}
+
+// EnvironmentURL returns the base URL of the AMO environment.  The production
+// environment is used if env is empty.
+func EnvironmentURL(env Environment) (u *url.URL, err error) {
+	if env == "" {
+		env = EnvironmentProduction
+	}
+
+	host, ok := environmentHosts[env]
+	if !ok {
+		return nil, fmt.Errorf("unknown environment %q, use %s, %s or %s", env,
+			EnvironmentProduction, EnvironmentStage, EnvironmentDev)
+	}
+
+	return &url.URL{
+		Scheme: "https",
+		Host:   host,
+	}, nil
+}
+
+// ErrVersionNotFound is returned when the store has no such version of the
+// extension.
+const ErrVersionNotFound errors.Error = "version not found"
+
+// ErrManualReview is returned when the extension can't be signed automatically
+// and has to be reviewed by a human.
+const ErrManualReview errors.Error = "extension won't be signed automatically"
 
 // maxReadLimit limits response size returned from the store api.
 const maxReadLimit = 10 * fileutil.MB
@@ -89,6 +150,97 @@ func (c Client) GenAuthHeader() (result string, err error) {
 type Store struct {
 	Client *Client
 	URL    *url.URL
+
+	// RequestTimeout is the timeout of a single request to the store api,
+	// DefaultRequestTimeout is used if zero.
+	RequestTimeout time.Duration
+	// PollInterval is the initial interval between the status checks, it is
+	// doubled after every check up to MaxPollInterval.  DefaultPollInterval is
+	// used if zero.
+	PollInterval time.Duration
+	// MaxPollInterval is the upper limit of the interval between the status
+	// checks, DefaultMaxPollInterval is used if zero.
+	MaxPollInterval time.Duration
+	// WaitTimeout is the maximum time to wait for the extension to be validated
+	// or signed, DefaultWaitTimeout is used if zero.
+	WaitTimeout time.Duration
+	// ReviewPollInterval is the interval between the status checks while the
+	// extension awaits a human review, DefaultReviewPollInterval is used if
+	// zero.
+	ReviewPollInterval time.Duration
+	// ReviewWaitTimeout is the maximum time to wait for a human review,
+	// DefaultReviewWaitTimeout is used if zero.
+	ReviewWaitTimeout time.Duration
+}
+
+// httpClient returns the client for the requests to the store api.
+func (s *Store) httpClient() *http.Client {
+	timeout := s.RequestTimeout
+	if timeout == 0 {
+		timeout = DefaultRequestTimeout
+	}
+
+	return &http.Client{Timeout: timeout}
+}
+
+// poll calls check until it reports that the awaited operation is done or
+// returns an error.  The interval between the calls starts at PollInterval and
+// is doubled after every call up to MaxPollInterval.  poll returns an error if
+// the operation isn't done within WaitTimeout.
+func (s *Store) poll(check func() (done bool, err error)) (err error) {
+	interval := s.PollInterval
+	if interval == 0 {
+		interval = DefaultPollInterval
+	}
+
+	maxInterval := s.MaxPollInterval
+	if maxInterval == 0 {
+		maxInterval = DefaultMaxPollInterval
+	}
+
+	waitTimeout := s.WaitTimeout
+	if waitTimeout == 0 {
+		waitTimeout = DefaultWaitTimeout
+	}
+
+	return pollWithBackoff(interval, maxInterval, waitTimeout, check)
+}
+
+// pollWithBackoff calls check until it reports that the awaited operation is
+// done, returns an error, or waitTimeout is exceeded.  The interval between the
+// calls is doubled after every call up to maxInterval.  The last call is made
+// at the deadline, so the whole waitTimeout is used.
+func pollWithBackoff(interval, maxInterval, waitTimeout time.Duration, check func() (done bool, err error)) (err error) {
+	deadline := time.Now().Add(waitTimeout)
+
+	for {
+		done, err := check()
+		if err != nil {
+			return err
+		}
+
+		if done {
+			return nil
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return fmt.Errorf("timeout after %s", waitTimeout)
+		}
+
+		wait := interval
+		if wait > remaining {
+			wait = remaining
+		}
+
+		log.Debug("retrying in: %s", wait)
+		time.Sleep(wait)
+
+		interval *= 2
+		if interval > maxInterval {
+			interval = maxInterval
+		}
+	}
 }
 
 // Manifest describes required fields parsed from the manifest.
@@ -134,7 +286,7 @@ func (s *Store) Status(appID string) (result []byte, err error) {
 
 	req.Header.Add("Authorization", authHeader)
 
-	client := &http.Client{Timeout: requestTimeout}
+	client := s.httpClient()
 
 	res, err := client.Do(req)
 	if err != nil {
@@ -155,6 +307,57 @@ func (s *Store) Status(appID string) (result []byte, err error) {
 	return body, nil
 }
 
+// Profile describes the account the credentials belong to.
+type Profile struct {
+	ID       int    `json:"id"`
+	Name     string `json:"name"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// Profile retrieves the profile of the account the credentials belong to.
+func (s *Store) Profile() (result *Profile, err error) {
+	const apiPath = "api/v5/accounts/profile/"
+
+	apiURL := s.URL.JoinPath(apiPath, "/").String()
+
+	req, err := http.NewRequest(http.MethodGet, apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	authHeader, err := s.Client.GenAuthHeader()
+	if err != nil {
+		return nil, fmt.Errorf("generating auth header: %w", err)
+	}
+
+	req.Header.Add("Authorization", authHeader)
+
+	client := s.httpClient()
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending request: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, res.Body.Close()) }()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxReadLimit))
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got code %d, body: %q", res.StatusCode, body)
+	}
+
+	err = json.Unmarshal(body, &result)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshalling response body: %s, error: %w", body, err)
+	}
+
+	return result, nil
+}
+
 type version struct {
 	ID      int    `json:"id"`
 	Version string `json:"version"`
@@ -191,7 +394,7 @@ func (s *Store) VersionID(appID, version string) (result string, err error) {
 
 	req.Header.Add("Authorization", authHeader)
 
-	client := &http.Client{Timeout: requestTimeout}
+	client := s.httpClient()
 	res, err := client.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("sending request: %w", err)
@@ -279,7 +482,7 @@ func (s *Store) UploadSource(appID, versionID, sourcePath string) (result []byte
 
 	req.Header.Add("Authorization", authHeader)
 
-	client := &http.Client{Timeout: requestTimeout}
+	client := s.httpClient()
 	res, err := client.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("sending request: %w", err)
@@ -321,6 +524,9 @@ type UploadStatus struct {
 	Valid            bool                `json:"valid"`
 	ValidationURL    string              `json:"validation_url"`
 	Version          string              `json:"version"`
+
+	// ValidationResults is nil until the upload is processed.
+	ValidationResults *ValidationReport `json:"validation_results"`
 }
 
 // ReviewedStatus represents reviewed status structure
@@ -373,7 +579,7 @@ func (s *Store) UploadStatus(appID, version string) (status *UploadStatus, err e
 
 	req.Header.Add("Authorization", authHeader)
 
-	client := &http.Client{Timeout: requestTimeout}
+	client := s.httpClient()
 
 	res, err := client.Do(req)
 	if err != nil {
@@ -386,6 +592,10 @@ func (s *Store) UploadStatus(appID, version string) (status *UploadStatus, err e
 		return nil, fmt.Errorf("reading response body: %w", err)
 	}
 
+	if res.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("appID: %s, version: %s: %w", appID, version, ErrVersionNotFound)
+	}
+
 	if res.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("unexpected code %d, body: %q", res.StatusCode, body)
 	}
@@ -404,28 +614,29 @@ func (s *Store) UploadStatus(appID, version string) (status *UploadStatus, err e
 
 // AwaitValidation awaits validation of the extension.
 func (s *Store) AwaitValidation(appID, version string) (err error) {
-	// TODO(maximtop): move constants to config
-	const retryInterval = time.Second
-	const maxAwaitTime = time.Minute * 20
+	err = s.poll(func() (done bool, err error) {
+		uploadStatus, err := s.UploadStatus(appID, version)
+		if err != nil {
+			return false, fmt.Errorf("getting upload status: %w", err)
+		}
 
-	startTime := time.Now()
+		if !uploadStatus.Processed {
+			log.Debug("upload not processed yet")
 
-	for {
-		if time.Since(startTime) > maxAwaitTime {
-			return fmt.Errorf("await validation timeout")
+			return false, nil
 		}
 
-		uploadStatus, err := s.UploadStatus(appID, version)
+		err = newValidationError(uploadStatus)
 		if err != nil {
-			return fmt.Errorf("getting upload status: %w", err)
-		}
-		if uploadStatus.Processed {
-			log.Debug("extension upload processed successfully")
-			break
-		} else {
-			log.Debug("upload not processed yet, retrying in: %s", retryInterval)
-			time.Sleep(retryInterval)
+			return false, err
 		}
+
+		log.Debug("extension upload processed successfully")
+
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("awaiting validation: %w", err)
 	}
 
 	return nil
@@ -485,7 +696,7 @@ func (s *Store) UploadNew(filePath string) (result []byte, err error) {
 	req.Header.Add("Authorization", authHeader)
 	req.Header.Add("Content-Type", writer.FormDataContentType())
 
-	client := http.Client{Timeout: requestTimeout}
+	client := s.httpClient()
 
 	res, err := client.Do(req)
 	if err != nil {
@@ -507,7 +718,8 @@ func (s *Store) UploadNew(filePath string) (result []byte, err error) {
 	return respBody, nil
 }
 
-// Insert uploads extension to the amo for the first time.
+// Insert uploads extension to the amo for the first time.  Uploading of the
+// source code is skipped if sourcepath is empty.
 func (s *Store) Insert(filepath, sourcepath string) (err error) {
 	log.Debug("start uploading new extension: %q, with source: %s", filepath, sourcepath)
 
@@ -529,6 +741,12 @@ func (s *Store) Insert(filepath, sourcepath string) (err error) {
 		return fmt.Errorf("[Insert] wasn't able to validate extension: %s, version: %s, due to: %w", appID, version, err)
 	}
 
+	if sourcepath == "" {
+		log.Debug("[Insert] no source provided for extension: %s, version: %s", appID, version)
+
+		return nil
+	}
+
 	versionID, err := s.VersionID(appID, version)
 	if err != nil {
 		return fmt.Errorf("[Insert] wasn't able to get version ID: %s, version: %s, due to: %w", appID, version, err)
@@ -575,7 +793,7 @@ func (s *Store) UploadUpdate(appID, version, filePath string) (result []byte, er
 		return nil, fmt.Errorf("[UploadUpdate] wasn't able to close form file due to: %w", err)
 	}
 
-	client := http.Client{Timeout: requestTimeout}
+	client := s.httpClient()
 
 	req, err := http.NewRequest(http.MethodPut, apiURL, body)
 	if err != nil {
@@ -612,6 +830,7 @@ func (s *Store) UploadUpdate(appID, version, filePath string) (result []byte, er
 
 // Update uploads new version of extension to the store
 // Before uploading it reads manifest.json for getting extension version and uuid.
+// Uploading of the source code is skipped if sourcepath is empty.
 func (s *Store) Update(filepath, sourcepath string) (err error) {
 	log.Debug("start uploading update for extension: %s, with source: %s", filepath, sourcepath)
 
@@ -633,6 +852,12 @@ func (s *Store) Update(filepath, sourcepath string) (err error) {
 		return fmt.Errorf("[Update] wasn't able to validate extension: %s, version: %s, due to: %w", appID, version, err)
 	}
 
+	if sourcepath == "" {
+		log.Debug("[Update] no source provided for extension: %s, version: %s", appID, version)
+
+		return nil
+	}
+
 	versionID, err := s.VersionID(appID, version)
 	if err != nil {
 		return fmt.Errorf("[Update] wasn't able to get version ID: %s, version: %s, due to: %w", appID, version, err)
@@ -650,20 +875,15 @@ func (s *Store) Update(filepath, sourcepath string) (err error) {
 func (s *Store) AwaitSigning(appID, version string) (err error) {
 	log.Debug("start waiting for signing of extension: %s", appID)
 
-	// TODO(maximtop): move constants to config
-	const retryInterval = time.Second
-	const maxAwaitTime = time.Minute * 20
-
-	startTime := time.Now()
-
-	for {
-		if time.Since(startTime) > maxAwaitTime {
-			return fmt.Errorf("await signing timeout")
+	err = s.poll(func() (done bool, err error) {
+		uploadStatus, err := s.UploadStatus(appID, version)
+		if err != nil {
+			return false, fmt.Errorf("[AwaitSigning] wasn't able to get upload status: %s, version: %s, due to: %w", appID, version, err)
 		}
 
-		uploadStatus, err := s.UploadStatus(appID, version)
+		err = newValidationError(uploadStatus)
 		if err != nil {
-			return fmt.Errorf("[AwaitSigning] wasn't able to get upload status: %s, version: %s, due to: %w", appID, version, err)
+			return false, err
 		}
 
 		signedAndReady := uploadStatus.Valid && uploadStatus.Active && bool(uploadStatus.Reviewed) && len(uploadStatus.Files) > 0
@@ -671,14 +891,21 @@ func (s *Store) AwaitSigning(appID, version string) (err error) {
 
 		if signedAndReady {
 			log.Debug("[AwaitSigning] extension is signed and ready: %s", appID)
-			return nil
+
+			return true, nil
 		} else if requiresManualReview {
-			return fmt.Errorf("[AwaitSigning] extension won't be signed automatically, status: %+v", uploadStatus)
-		} else {
-			log.Debug("[AwaitSigning] extension is not processed yet, retry in %s", retryInterval)
-			time.Sleep(retryInterval)
+			return false, fmt.Errorf("[AwaitSigning] status: %+v: %w", uploadStatus, ErrManualReview)
 		}
+
+		log.Debug("[AwaitSigning] extension is not processed yet")
+
+		return false, nil
+	})
+	if err != nil {
+		return fmt.Errorf("awaiting signing: %w", err)
 	}
+
+	return nil
 }
 
 // DownloadSigned downloads signed extension.
@@ -696,7 +923,7 @@ func (s *Store) DownloadSigned(appID, version string) (err error) {
 
 	downloadURL := uploadStatus.Files[0].DownloadURL
 
-	client := http.Client{Timeout: requestTimeout}
+	client := s.httpClient()
 
 	req, err := http.NewRequest(http.MethodGet, downloadURL, nil)
 	if err != nil {
@@ -743,9 +970,19 @@ func (s *Store) DownloadSigned(appID, version string) (err error) {
 	return nil
 }
 
+// SignOptions represents the options for the signing.
+type SignOptions struct {
+	// AwaitReview makes Sign wait for a human review instead of failing when
+	// the extension can't be signed automatically.
+	AwaitReview bool
+	// Resume makes Sign skip uploading if the version is already uploaded, so
+	// that an interrupted signing can be continued.
+	Resume bool
+}
+
 // Sign uploads the extension to the store, waits for signing, downloads and saves the signed
 // extension in the directory
-func (s *Store) Sign(filepath string) (err error) {
+func (s *Store) Sign(filepath string, signOptions SignOptions) (err error) {
 	log.Debug("start signing extension: %q", filepath)
 
 	manifest, err := parseManifest(filepath)
@@ -756,12 +993,27 @@ func (s *Store) Sign(filepath string) (err error) {
 	appID := manifest.Applications.Gecko.ID
 	version := manifest.Version
 
-	_, err = s.UploadUpdate(appID, version, filepath)
-	if err != nil {
-		return fmt.Errorf("[Sign] wasn't able to upload extension: %s, version: %s, due to: %w", appID, version, err)
+	uploaded := false
+	if signOptions.Resume {
+		uploaded, err = s.isUploaded(appID, version)
+		if err != nil {
+			return fmt.Errorf("[Sign] wasn't able to check upload of extension: %s, version: %s, due to: %w", appID, version, err)
+		}
+	}
+
+	if uploaded {
+		log.Debug("[Sign] extension: %s, version: %s is already uploaded, resuming", appID, version)
+	} else {
+		_, err = s.UploadUpdate(appID, version, filepath)
+		if err != nil {
+			return fmt.Errorf("[Sign] wasn't able to upload extension: %s, version: %s, due to: %w", appID, version, err)
+		}
 	}
 
 	err = s.AwaitSigning(appID, version)
+	if signOptions.AwaitReview && errors.Is(err, ErrManualReview) {
+		err = s.AwaitReview(appID, version)
+	}
 	if err != nil {
 		return fmt.Errorf("[Sign] wasn't able to wait for signing of extension: %s, version: %s, due to: %w", appID, version, err)
 	}
@@ -1,6 +1,7 @@
 package firefox_test
 
 import (
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -192,3 +193,283 @@ func TestUploadSource(t *testing.T) {
 
 	assert.Equal(response, string(uploadResponse))
 }
+
+func TestAwaitValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	client := firefox.NewClient(firefox.ClientConfig{ClientID: clientID, ClientSecret: clientSecret})
+
+	const processedAfter = 3
+	requests := 0
+
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(http.MethodGet, r.Method)
+		assert.Contains(r.URL.Path, path.Join("api/v5/addons", appID, "versions", version))
+
+		requests++
+
+		processed := requests >= processedAfter
+		uploadStatus := firefox.UploadStatus{Processed: processed, Valid: processed}
+
+		err := json.NewEncoder(w).Encode(uploadStatus)
+		require.NoError(t, err)
+	}))
+	defer storeServer.Close()
+
+	storeURL, err := url.Parse(storeServer.URL)
+	require.NoError(t, err)
+
+	store := firefox.Store{
+		Client:       &client,
+		URL:          storeURL,
+		PollInterval: time.Millisecond,
+		WaitTimeout:  time.Second,
+	}
+
+	err = store.AwaitValidation(appID, version)
+	require.NoError(t, err)
+
+	assert.Equal(processedAfter, requests)
+}
+
+func TestAwaitValidation_timeout(t *testing.T) {
+	client := firefox.NewClient(firefox.ClientConfig{ClientID: clientID, ClientSecret: clientSecret})
+
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		err := json.NewEncoder(w).Encode(firefox.UploadStatus{})
+		require.NoError(t, err)
+	}))
+	defer storeServer.Close()
+
+	storeURL, err := url.Parse(storeServer.URL)
+	require.NoError(t, err)
+
+	store := firefox.Store{
+		Client:          &client,
+		URL:             storeURL,
+		PollInterval:    time.Millisecond,
+		MaxPollInterval: 4 * time.Millisecond,
+		WaitTimeout:     20 * time.Millisecond,
+	}
+
+	err = store.AwaitValidation(appID, version)
+	assert.ErrorContains(t, err, "timeout")
+}
+
+func TestAwaitValidation_deadline(t *testing.T) {
+	client := firefox.NewClient(firefox.ClientConfig{ClientID: clientID, ClientSecret: clientSecret})
+
+	var start time.Time
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if start.IsZero() {
+			start = time.Now()
+		}
+
+		// the upload is processed after the second poll, but before the
+		// deadline
+		status := firefox.UploadStatus{Processed: time.Since(start) > 200*time.Millisecond, Valid: true}
+		err := json.NewEncoder(w).Encode(status)
+		require.NoError(t, err)
+	}))
+	defer storeServer.Close()
+
+	storeURL, err := url.Parse(storeServer.URL)
+	require.NoError(t, err)
+
+	store := firefox.Store{
+		Client:          &client,
+		URL:             storeURL,
+		PollInterval:    100 * time.Millisecond,
+		MaxPollInterval: time.Second,
+		WaitTimeout:     250 * time.Millisecond,
+	}
+
+	err = store.AwaitValidation(appID, version)
+	assert.NoError(t, err)
+}
+
+func TestAwaitValidation_invalid(t *testing.T) {
+	assert := assert.New(t)
+
+	client := firefox.NewClient(firefox.ClientConfig{ClientID: clientID, ClientSecret: clientSecret})
+
+	const validationURL = "https://addons.mozilla.org/en-US/developers/upload/test"
+
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write([]byte(`{
+			"processed": true,
+			"valid": false,
+			"validation_url": "` + validationURL + `",
+			"validation_results": {
+				"errors": 1,
+				"warnings": 0,
+				"notices": 0,
+				"messages": [{
+					"type": "error",
+					"message": "The \"version\" property must be a string.",
+					"description": ["See the documentation.", "Fix the manifest."],
+					"file": "manifest.json",
+					"line": 3,
+					"column": 5
+				}]
+			}
+		}`))
+		require.NoError(t, err)
+	}))
+	defer storeServer.Close()
+
+	storeURL, err := url.Parse(storeServer.URL)
+	require.NoError(t, err)
+
+	store := firefox.Store{
+		Client:       &client,
+		URL:          storeURL,
+		PollInterval: time.Millisecond,
+		WaitTimeout:  time.Second,
+	}
+
+	err = store.AwaitValidation(appID, version)
+	require.Error(t, err)
+
+	var validationErr *firefox.ValidationError
+	require.True(t, errors.As(err, &validationErr))
+
+	assert.Equal(validationURL, validationErr.URL)
+	require.NotNil(t, validationErr.Report)
+	assert.Equal(1, validationErr.Report.Errors)
+	require.Len(t, validationErr.Report.Messages, 1)
+
+	msg := validationErr.Report.Messages[0]
+	assert.Equal("manifest.json:3:5", msg.Location())
+	assert.Equal(firefox.ValidationText("See the documentation.\nFix the manifest."), msg.Description)
+}
+
+func TestAwaitReview(t *testing.T) {
+	testCases := []struct {
+		name       string
+		statuses   []string
+		wantErr    error
+		wantChecks int
+	}{{
+		name: "approved",
+		statuses: []string{
+			`{"processed": true, "valid": true, "reviewed": false}`,
+			`{"processed": true, "valid": true, "reviewed": "2022-09-01T10:00:00Z", "passed_review": true}`,
+			`{"processed": true, "valid": true, "reviewed": "2022-09-01T10:00:00Z", "passed_review": true, "active": true, "files": [{"signed": true}]}`,
+		},
+		wantErr:    nil,
+		wantChecks: 3,
+	}, {
+		name: "rejected",
+		statuses: []string{
+			`{"processed": true, "valid": true, "reviewed": false}`,
+			`{"processed": true, "valid": true, "reviewed": "2022-09-01T10:00:00Z", "passed_review": false}`,
+		},
+		wantErr:    firefox.ErrRejected,
+		wantChecks: 2,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			client := firefox.NewClient(firefox.ClientConfig{ClientID: clientID, ClientSecret: clientSecret})
+
+			checks := 0
+
+			storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Contains(t, r.URL.Path, path.Join("api/v5/addons", appID, "versions", version))
+
+				_, err := w.Write([]byte(tc.statuses[checks]))
+				require.NoError(t, err)
+
+				checks++
+			}))
+			defer storeServer.Close()
+
+			storeURL, err := url.Parse(storeServer.URL)
+			require.NoError(t, err)
+
+			store := firefox.Store{
+				Client:             &client,
+				URL:                storeURL,
+				ReviewPollInterval: time.Millisecond,
+				ReviewWaitTimeout:  time.Second,
+			}
+
+			err = store.AwaitReview(appID, version)
+			if tc.wantErr != nil {
+				assert.ErrorIs(t, err, tc.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.wantChecks, checks)
+		})
+	}
+}
+
+func TestProfile(t *testing.T) {
+	assert := assert.New(t)
+
+	client := firefox.NewClient(firefox.ClientConfig{ClientID: clientID, ClientSecret: clientSecret})
+
+	storeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(http.MethodGet, r.Method)
+		assert.Equal("/api/v5/accounts/profile/", r.URL.Path)
+		assert.Contains(r.Header.Get("Authorization"), "JWT ")
+
+		_, err := w.Write([]byte(`{"id": 1, "name": "Test", "username": "test_user", "email": "test@example.org"}`))
+		require.NoError(t, err)
+	}))
+	defer storeServer.Close()
+
+	storeURL, err := url.Parse(storeServer.URL)
+	require.NoError(t, err)
+
+	store := firefox.Store{
+		Client: &client,
+		URL:    storeURL,
+	}
+
+	profile, err := store.Profile()
+	require.NoError(t, err)
+
+	assert.Equal("test_user", profile.Username)
+	assert.Equal("test@example.org", profile.Email)
+}
+
+func TestEnvironmentURL(t *testing.T) {
+	testCases := []struct {
+		env     firefox.Environment
+		want    string
+		wantErr bool
+	}{{
+		env:  "",
+		want: "https://addons.mozilla.org",
+	}, {
+		env:  firefox.EnvironmentProduction,
+		want: "https://addons.mozilla.org",
+	}, {
+		env:  firefox.EnvironmentStage,
+		want: "https://addons.allizom.org",
+	}, {
+		env:  firefox.EnvironmentDev,
+		want: "https://addons-dev.allizom.org",
+	}, {
+		env:     "test",
+		wantErr: true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.env), func(t *testing.T) {
+			u, err := firefox.EnvironmentURL(tc.env)
+			if tc.wantErr {
+				assert.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, u.String())
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package firefox
+
+import (
+	"fmt"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/log"
+)
+
+// ErrRejected is returned when the extension version is rejected by a
+// reviewer.
+const ErrRejected errors.Error = "extension version is rejected"
+
+// AwaitReview waits for a human review of the extension version, which may
+// take days.  It returns nil when the version is approved and signed, and
+// ErrRejected when the version is rejected.  AwaitReview can be called again
+// after an interruption, since it only polls the store.
+func (s *Store) AwaitReview(appID, version string) (err error) {
+	log.Debug("start waiting for review of extension: %s, version: %s", appID, version)
+
+	interval := s.ReviewPollInterval
+	if interval == 0 {
+		interval = DefaultReviewPollInterval
+	}
+
+	waitTimeout := s.ReviewWaitTimeout
+	if waitTimeout == 0 {
+		waitTimeout = DefaultReviewWaitTimeout
+	}
+
+	err = pollWithBackoff(interval, interval, waitTimeout, func() (done bool, err error) {
+		uploadStatus, err := s.UploadStatus(appID, version)
+		if err != nil {
+			return false, fmt.Errorf("getting upload status: %w", err)
+		}
+
+		err = newValidationError(uploadStatus)
+		if err != nil {
+			return false, err
+		}
+
+		if !uploadStatus.Reviewed {
+			log.Debug("[AwaitReview] extension: %s, version: %s is waiting for review", appID, version)
+
+			return false, nil
+		}
+
+		if !uploadStatus.PassedReview {
+			return false, fmt.Errorf("status: %+v: %w", uploadStatus, ErrRejected)
+		}
+
+		if !uploadStatus.Active || len(uploadStatus.Files) == 0 {
+			log.Debug("[AwaitReview] extension: %s, version: %s is approved, waiting for signed files", appID, version)
+
+			return false, nil
+		}
+
+		log.Debug("[AwaitReview] extension: %s, version: %s is approved and signed", appID, version)
+
+		return true, nil
+	})
+	if err != nil {
+		return fmt.Errorf("awaiting review: %w", err)
+	}
+
+	return nil
+}
+
+// isUploaded returns true if the version of the extension is already uploaded
+// to the store.
+func (s *Store) isUploaded(appID, version string) (ok bool, err error) {
+	_, err = s.UploadStatus(appID, version)
+	if errors.Is(err, ErrVersionNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
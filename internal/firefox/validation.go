@@ -0,0 +1,108 @@
+package firefox
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ValidationText represents text of the validation message.  AMO returns it
+// either as a string or as a list of strings.
+type ValidationText string
+
+// UnmarshalJSON parses ValidationText.
+func (t *ValidationText) UnmarshalJSON(b []byte) error {
+	var lines []string
+
+	err := json.Unmarshal(b, &lines)
+	if err == nil {
+		*t = ValidationText(strings.Join(lines, "\n"))
+
+		return nil
+	}
+
+	var text string
+
+	err = json.Unmarshal(b, &text)
+	if err != nil {
+		return fmt.Errorf("unmarshalling validation text %s: %w", b, err)
+	}
+
+	*t = ValidationText(text)
+
+	return nil
+}
+
+// ValidationMessage describes a single message of the validation report.
+type ValidationMessage struct {
+	Type        string         `json:"type"`
+	Message     ValidationText `json:"message"`
+	Description ValidationText `json:"description"`
+	File        string         `json:"file"`
+	Line        int            `json:"line"`
+	Column      int            `json:"column"`
+}
+
+// Location returns location of the message in the extension files in the
+// file:line:column form, or an empty string if the message has no file.
+func (m ValidationMessage) Location() (location string) {
+	if m.File == "" {
+		return ""
+	}
+
+	location = m.File
+	if m.Line > 0 {
+		location += fmt.Sprintf(":%d", m.Line)
+
+		if m.Column > 0 {
+			location += fmt.Sprintf(":%d", m.Column)
+		}
+	}
+
+	return location
+}
+
+// ValidationReport describes results of the extension validation.
+type ValidationReport struct {
+	Errors   int                 `json:"errors"`
+	Warnings int                 `json:"warnings"`
+	Notices  int                 `json:"notices"`
+	Messages []ValidationMessage `json:"messages"`
+}
+
+// ValidationError is returned when the store has processed the upload and
+// found it invalid.
+type ValidationError struct {
+	// Report is nil if the store didn't return validation results.
+	Report *ValidationReport
+	// URL is the address of the validation results page.
+	URL string
+}
+
+// Error implements the error interface for *ValidationError.
+func (e *ValidationError) Error() string {
+	if e.Report == nil {
+		return fmt.Sprintf("extension is invalid, see %s", e.URL)
+	}
+
+	return fmt.Sprintf(
+		"extension is invalid: %d errors, %d warnings, %d notices, see %s",
+		e.Report.Errors,
+		e.Report.Warnings,
+		e.Report.Notices,
+		e.URL,
+	)
+}
+
+// newValidationError returns a *ValidationError if the processed upload is
+// invalid, otherwise it returns nil.
+func newValidationError(status *UploadStatus) (err error) {
+	if !status.Processed || status.Valid {
+		return nil
+	}
+
+	return &ValidationError{
+		Report: status.ValidationResults,
+		URL:    status.ValidationURL,
+	}
+}
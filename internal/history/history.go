@@ -0,0 +1,259 @@
+// Package history contains the local database of the release attempts, the
+// status changes and the statistics of the extensions.
+package history
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/maximtop/extdash/internal/notify"
+	"github.com/maximtop/extdash/internal/stats"
+	bolt "go.etcd.io/bbolt"
+)
+
+// openTimeout is the time to wait for the database to be released by another
+// process.
+const openTimeout = 5 * time.Second
+
+// recordsBucket is the name of the bucket with the records.
+var recordsBucket = []byte("records")
+
+// Kind is the kind of the record.
+type Kind string
+
+// Record kinds.
+const (
+	// KindRelease is the attempt to release the extension.
+	KindRelease Kind = "release"
+	// KindStatus is the change of the extension status in the store.
+	KindStatus Kind = "status"
+	// KindStats is the statistics sample.
+	KindStats Kind = "stats"
+	// KindAlert is the alert sent by the watcher.
+	KindAlert Kind = "alert"
+)
+
+// Record is the entry of the history.
+type Record struct {
+	// ID is assigned by the database and increases with every record.
+	ID        uint64    `json:"id"`
+	Time      time.Time `json:"time"`
+	Kind      Kind      `json:"kind"`
+	Extension string    `json:"extension,omitempty"`
+	Store     string    `json:"store"`
+	AppID     string    `json:"app_id,omitempty"`
+	// Operation is the release command, e.g. update or publish.
+	Operation string `json:"operation,omitempty"`
+	// Rule is the alert rule of the KindAlert record.
+	Rule           string `json:"rule,omitempty"`
+	Version        string `json:"version,omitempty"`
+	PendingVersion string `json:"pending_version,omitempty"`
+	PreviousState  string `json:"previous_state,omitempty"`
+	// State is the status of the extension or the outcome of the release,
+	// succeeded or failed.
+	State string `json:"state,omitempty"`
+	// Message is the error of the failed release or the details of the alert.
+	Message string `json:"message,omitempty"`
+	// Response is the response of the store to the release.
+	Response json.RawMessage `json:"response,omitempty"`
+	// Stats is the statistics sample of the KindStats record.
+	Stats *stats.Sample `json:"stats,omitempty"`
+}
+
+// Release outcomes saved to Record.State.
+const (
+	ReleaseSucceeded = "succeeded"
+	ReleaseFailed    = "failed"
+)
+
+// FromEvent returns the record of the status change or of the release event.
+func FromEvent(event *notify.Event) (r *Record) {
+	r = &Record{
+		Time:           event.Time,
+		Kind:           KindStatus,
+		Extension:      event.Extension,
+		Store:          event.Store,
+		AppID:          event.AppID,
+		Operation:      event.Operation,
+		Rule:           event.Rule,
+		Version:        event.Version,
+		PendingVersion: event.PendingVersion,
+		PreviousState:  event.PreviousState,
+		State:          event.State,
+		Message:        event.Message,
+	}
+
+	switch event.Type {
+	case notify.EventReleaseSucceeded:
+		r.Kind, r.State = KindRelease, ReleaseSucceeded
+	case notify.EventReleaseFailed:
+		r.Kind, r.State = KindRelease, ReleaseFailed
+	case notify.EventAlert:
+		r.Kind = KindAlert
+	}
+
+	return r
+}
+
+// FromSample returns the record of the statistics sample.
+func FromSample(sample *stats.Sample) (r *Record) {
+	return &Record{
+		Time:      sample.Time,
+		Kind:      KindStats,
+		Extension: sample.Extension,
+		Store:     sample.Store,
+		AppID:     sample.AppID,
+		Version:   sample.Version,
+		Stats:     sample,
+	}
+}
+
+// Filter selects the records, the empty fields select all records.
+type Filter struct {
+	Kind      Kind
+	Extension string
+	Store     string
+	AppID     string
+	// Version selects the records with the version either published or
+	// pending.
+	Version string
+	Since   time.Time
+}
+
+// Match returns true if the record is selected by the filter.
+func (f *Filter) Match(r *Record) (ok bool) {
+	return (f.Kind == "" || f.Kind == r.Kind) &&
+		(f.Extension == "" || f.Extension == r.Extension) &&
+		(f.Store == "" || f.Store == r.Store) &&
+		(f.AppID == "" || f.AppID == r.AppID) &&
+		(f.Version == "" || f.Version == r.Version || f.Version == r.PendingVersion) &&
+		!r.Time.Before(f.Since)
+}
+
+// DB is the history database, it is the single file, which is locked while
+// the database is open.
+type DB struct {
+	db *bolt.DB
+}
+
+// Open opens the database and creates it if it doesn't exist.  It waits for
+// the other process to close the database.
+func Open(path string) (db *DB, err error) {
+	err = os.MkdirAll(filepath.Dir(path), 0o700)
+	if err != nil {
+		return nil, fmt.Errorf("creating directory: %w", err)
+	}
+
+	boltDB, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: openTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("opening database: %w", err)
+	}
+
+	err = boltDB.Update(func(tx *bolt.Tx) (txErr error) {
+		_, txErr = tx.CreateBucketIfNotExists(recordsBucket)
+
+		return txErr
+	})
+	if err != nil {
+		return nil, errors.WithDeferred(fmt.Errorf("creating bucket: %w", err), boltDB.Close())
+	}
+
+	return &DB{db: boltDB}, nil
+}
+
+// Close closes the database.
+func (db *DB) Close() (err error) {
+	return db.db.Close()
+}
+
+// Add saves the records and sets their IDs.
+func (db *DB) Add(records ...*Record) (err error) {
+	return db.db.Update(func(tx *bolt.Tx) (txErr error) {
+		bucket := tx.Bucket(recordsBucket)
+
+		for _, r := range records {
+			r.ID, txErr = bucket.NextSequence()
+			if txErr != nil {
+				return fmt.Errorf("getting id: %w", txErr)
+			}
+
+			var data []byte
+			data, txErr = json.Marshal(r)
+			if txErr != nil {
+				return fmt.Errorf("marshaling record: %w", txErr)
+			}
+
+			txErr = bucket.Put(recordKey(r.ID), data)
+			if txErr != nil {
+				return fmt.Errorf("saving record: %w", txErr)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Find returns the records selected by the filter in the order they were
+// added.  All records are returned if filter is nil.
+func (db *DB) Find(filter *Filter) (records []*Record, err error) {
+	err = db.db.View(func(tx *bolt.Tx) (txErr error) {
+		return tx.Bucket(recordsBucket).ForEach(func(_, data []byte) (itemErr error) {
+			r := &Record{}
+
+			itemErr = json.Unmarshal(data, r)
+			if itemErr != nil {
+				return fmt.Errorf("unmarshaling record: %w", itemErr)
+			}
+
+			if filter == nil || filter.Match(r) {
+				records = append(records, r)
+			}
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// recordKey returns the key of the record, the keys are sorted by ID.
+func recordKey(id uint64) (key []byte) {
+	key = make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+
+	return key
+}
+
+// Add opens the database at the path, saves the records and closes it.
+func Add(path string, records ...*Record) (err error) {
+	db, err := Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { err = errors.WithDeferred(err, db.Close()) }()
+
+	return db.Add(records...)
+}
+
+// Notifier saves the events to the database.  The database is opened for
+// every event, so that the long-running watcher doesn't lock it.
+type Notifier struct {
+	Path string
+}
+
+// type check
+var _ notify.Notifier = (*Notifier)(nil)
+
+// Notify implements the notify.Notifier interface for *Notifier.
+func (n *Notifier) Notify(_ context.Context, event *notify.Event) (err error) {
+	return Add(n.Path, FromEvent(event))
+}
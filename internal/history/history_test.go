@@ -0,0 +1,157 @@
+package history_test
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maximtop/extdash/internal/history"
+	"github.com/maximtop/extdash/internal/notify"
+	"github.com/maximtop/extdash/internal/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDB(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "history", "history.db")
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	db, err := history.Open(dbPath)
+	require.NoError(t, err)
+
+	records := []*history.Record{{
+		Time:      start,
+		Kind:      history.KindRelease,
+		Store:     "edge",
+		AppID:     "test",
+		Operation: "update",
+		Version:   "4.1.2",
+		State:     history.ReleaseSucceeded,
+		Response:  json.RawMessage(`{"id":"op"}`),
+	}, {
+		Time:          start.Add(time.Hour),
+		Kind:          history.KindStatus,
+		Store:         "firefox",
+		AppID:         "other",
+		Version:       "4.1.2",
+		PreviousState: "in_review",
+		State:         "published",
+	}, {
+		Time:    start.Add(24 * time.Hour),
+		Kind:    history.KindStats,
+		Store:   "edge",
+		AppID:   "test",
+		Version: "4.1.2",
+		Stats:   &stats.Sample{Users: 10},
+	}}
+
+	err = db.Add(records...)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 3}, []uint64{records[0].ID, records[1].ID, records[2].ID})
+
+	require.NoError(t, db.Close())
+
+	// records are kept after reopening
+	db, err = history.Open(dbPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	testCases := []struct {
+		name    string
+		filter  *history.Filter
+		wantIDs []uint64
+	}{{
+		name:    "all",
+		filter:  nil,
+		wantIDs: []uint64{1, 2, 3},
+	}, {
+		name:    "store",
+		filter:  &history.Filter{Store: "edge", AppID: "test"},
+		wantIDs: []uint64{1, 3},
+	}, {
+		name:    "kind",
+		filter:  &history.Filter{Kind: history.KindStatus},
+		wantIDs: []uint64{2},
+	}, {
+		name:    "since",
+		filter:  &history.Filter{Version: "4.1.2", Since: start.Add(time.Hour)},
+		wantIDs: []uint64{2, 3},
+	}, {
+		name:    "none",
+		filter:  &history.Filter{Version: "4.1.3"},
+		wantIDs: nil,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			found, findErr := db.Find(tc.filter)
+			require.NoError(t, findErr)
+
+			var ids []uint64
+			for _, r := range found {
+				ids = append(ids, r.ID)
+			}
+
+			assert.Equal(t, tc.wantIDs, ids)
+		})
+	}
+
+	found, err := db.Find(&history.Filter{Kind: history.KindRelease})
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.JSONEq(t, `{"id":"op"}`, string(found[0].Response))
+}
+
+func TestFromEvent(t *testing.T) {
+	event := &notify.Event{
+		Time:      time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		Type:      notify.EventReleaseFailed,
+		Extension: "test",
+		Store:     "chrome",
+		AppID:     "app",
+		Operation: "publish",
+		Message:   "rejected",
+	}
+
+	r := history.FromEvent(event)
+	assert.Equal(t, history.KindRelease, r.Kind)
+	assert.Equal(t, history.ReleaseFailed, r.State)
+	assert.Equal(t, "publish", r.Operation)
+	assert.Equal(t, "rejected", r.Message)
+
+	event = &notify.Event{
+		Type:          notify.EventStatusChanged,
+		Store:         "chrome",
+		PreviousState: "in_review",
+		State:         "published",
+	}
+
+	r = history.FromEvent(event)
+	assert.Equal(t, history.KindStatus, r.Kind)
+	assert.Equal(t, "published", r.State)
+}
+
+func TestNotifier(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "history.db")
+	n := &history.Notifier{Path: dbPath}
+
+	for i := 0; i < 2; i++ {
+		err := n.Notify(context.Background(), &notify.Event{
+			Type:    notify.EventStatusChanged,
+			Store:   "firefox",
+			State:   "published",
+			Version: "1.0.0",
+		})
+		require.NoError(t, err)
+	}
+
+	db, err := history.Open(dbPath)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	found, err := db.Find(nil)
+	require.NoError(t, err)
+	assert.Len(t, found, 2)
+}
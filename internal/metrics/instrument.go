@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/maximtop/extdash/internal/api"
+	"github.com/maximtop/extdash/internal/stats"
+	"github.com/maximtop/extdash/internal/watch"
+)
+
+// Endpoints of the store APIs counted besides the API operations.
+const (
+	EndpointStatus = "status"
+	EndpointStats  = "stats"
+)
+
+// Source counts the errors of the status source.
+type Source struct {
+	Source watch.Source
+	Errors *Errors
+	Store  string
+}
+
+// type check
+var _ watch.Source = (*Source)(nil)
+
+// Snapshot implements the watch.Source interface for *Source.
+func (s *Source) Snapshot(appID string) (snapshot *watch.Snapshot, err error) {
+	snapshot, err = s.Source.Snapshot(appID)
+	if err != nil {
+		s.Errors.Inc(s.Store, EndpointStatus)
+	}
+
+	return snapshot, err
+}
+
+// Collector counts the errors of the statistics collector.
+type Collector struct {
+	Collector stats.Collector
+	Errors    *Errors
+	Store     string
+}
+
+// type check
+var _ stats.Collector = (*Collector)(nil)
+
+// Collect implements the stats.Collector interface for *Collector.
+func (c *Collector) Collect(appID string) (sample *stats.Sample, err error) {
+	sample, err = c.Collector.Collect(appID)
+	if err != nil {
+		c.Errors.Inc(c.Store, EndpointStats)
+	}
+
+	return sample, err
+}
+
+// Executor returns the executor of the API jobs, which counts the errors of
+// the operations.
+func Executor(executor api.Executor, errs *Errors) (instrumented api.Executor) {
+	return func(ctx context.Context, req *api.Request, logf api.Logf) (result any, err error) {
+		result, err = executor(ctx, req, logf)
+		if err != nil {
+			errs.Inc(req.Store, string(req.Operation))
+		}
+
+		return result, err
+	}
+}
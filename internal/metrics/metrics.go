@@ -0,0 +1,166 @@
+// Package metrics contains the metrics of the extensions in the Prometheus text
+// exposition format and the counters of the store API errors.
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the content type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Type is the type of the metric.
+type Type string
+
+// Metric types.
+const (
+	TypeGauge   Type = "gauge"
+	TypeCounter Type = "counter"
+)
+
+// Label is the name and the value of the label.
+type Label struct {
+	Name  string
+	Value string
+}
+
+// Sample is the value of the metric with the labels.
+type Sample struct {
+	Labels []Label
+	Value  float64
+}
+
+// Family is the metric with its samples.
+type Family struct {
+	Name    string
+	Help    string
+	Type    Type
+	Samples []*Sample
+}
+
+// Add appends the sample with the labels given as the name and value pairs.
+func (f *Family) Add(value float64, labels ...string) {
+	s := &Sample{Value: value}
+	for i := 0; i+1 < len(labels); i += 2 {
+		s.Labels = append(s.Labels, Label{Name: labels[i], Value: labels[i+1]})
+	}
+
+	f.Samples = append(f.Samples, s)
+}
+
+// labelReplacer escapes the label values.
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// helpReplacer escapes the help strings.
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+// formatValue returns the value in the exposition format.
+func formatValue(v float64) (s string) {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// Write writes the families in the Prometheus text exposition format.  The
+// families without samples are skipped.
+func Write(w io.Writer, families ...*Family) (err error) {
+	bw := bufio.NewWriter(w)
+
+	for _, f := range families {
+		if len(f.Samples) == 0 {
+			continue
+		}
+
+		fmt.Fprintf(bw, "# HELP %s %s\n", f.Name, helpReplacer.Replace(f.Help))
+		fmt.Fprintf(bw, "# TYPE %s %s\n", f.Name, f.Type)
+
+		for _, s := range f.Samples {
+			bw.WriteString(f.Name)
+
+			if len(s.Labels) > 0 {
+				labels := make([]string, 0, len(s.Labels))
+				for _, l := range s.Labels {
+					labels = append(labels, l.Name+`="`+labelReplacer.Replace(l.Value)+`"`)
+				}
+
+				bw.WriteString("{" + strings.Join(labels, ",") + "}")
+			}
+
+			bw.WriteString(" " + formatValue(s.Value) + "\n")
+		}
+	}
+
+	return bw.Flush()
+}
+
+// errorKey is the key of the error counter.
+type errorKey struct {
+	store    string
+	endpoint string
+}
+
+// Errors counts the errors of the store APIs by the store and the endpoint.
+// The zero value is ready to use.
+type Errors struct {
+	mu     sync.Mutex
+	counts map[errorKey]uint64
+}
+
+// Inc increments the counter of the errors of the store endpoint, e.g.
+// "status" or "upload".
+func (e *Errors) Inc(store, endpoint string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.counts == nil {
+		e.counts = map[errorKey]uint64{}
+	}
+
+	e.counts[errorKey{store: store, endpoint: endpoint}]++
+}
+
+// Family returns the counters as the metric family, the samples are sorted by
+// the store and the endpoint.
+func (e *Errors) Family() (f *Family) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	keys := make([]errorKey, 0, len(e.counts))
+	for k := range e.counts {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].store != keys[j].store {
+			return keys[i].store < keys[j].store
+		}
+
+		return keys[i].endpoint < keys[j].endpoint
+	})
+
+	f = &Family{
+		Name: "extdash_store_api_errors_total",
+		Help: "Number of the failed requests to the store API.",
+		Type: TypeCounter,
+	}
+
+	for _, k := range keys {
+		f.Add(float64(e.counts[k]), "store", k.store, "endpoint", k.endpoint)
+	}
+
+	return f
+}
@@ -0,0 +1,81 @@
+package metrics_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/maximtop/extdash/internal/api"
+	"github.com/maximtop/extdash/internal/metrics"
+	"github.com/maximtop/extdash/internal/watch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	gauge := &metrics.Family{
+		Name: "test_gauge",
+		Help: "Test gauge\nwith two lines.",
+		Type: metrics.TypeGauge,
+	}
+	gauge.Add(1.5, "name", `quoted "value"`)
+	gauge.Add(math.Inf(1))
+
+	empty := &metrics.Family{
+		Name: "test_empty",
+		Type: metrics.TypeCounter,
+	}
+
+	b := &bytes.Buffer{}
+	require.NoError(t, metrics.Write(b, gauge, empty))
+
+	want := `# HELP test_gauge Test gauge\nwith two lines.
+# TYPE test_gauge gauge
+test_gauge{name="quoted \"value\""} 1.5
+test_gauge +Inf
+`
+	assert.Equal(t, want, b.String())
+}
+
+// failingSource always fails.
+type failingSource struct{}
+
+func (failingSource) Snapshot(_ string) (snapshot *watch.Snapshot, err error) {
+	return nil, fmt.Errorf("test error")
+}
+
+func TestErrors(t *testing.T) {
+	errs := &metrics.Errors{}
+
+	source := &metrics.Source{Source: failingSource{}, Errors: errs, Store: "firefox"}
+	for i := 0; i < 2; i++ {
+		_, err := source.Snapshot("test")
+		require.Error(t, err)
+	}
+
+	executor := metrics.Executor(func(_ context.Context, req *api.Request, _ api.Logf) (result any, err error) {
+		if req.Store == "edge" {
+			return nil, fmt.Errorf("test error")
+		}
+
+		return "ok", nil
+	}, errs)
+
+	_, err := executor(context.Background(), &api.Request{Operation: api.OperationPublish, Store: "edge"}, nil)
+	require.Error(t, err)
+
+	_, err = executor(context.Background(), &api.Request{Operation: api.OperationPublish, Store: "chrome"}, nil)
+	require.NoError(t, err)
+
+	b := &bytes.Buffer{}
+	require.NoError(t, metrics.Write(b, errs.Family()))
+
+	want := `# HELP extdash_store_api_errors_total Number of the failed requests to the store API.
+# TYPE extdash_store_api_errors_total counter
+extdash_store_api_errors_total{store="edge",endpoint="publish"} 1
+extdash_store_api_errors_total{store="firefox",endpoint="status"} 2
+`
+	assert.Equal(t, want, b.String())
+}
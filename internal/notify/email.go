@@ -0,0 +1,253 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// EmailSecurity is the way the connection to the SMTP server is secured.
+type EmailSecurity string
+
+// Email security modes.
+const (
+	// EmailSecurityStartTLS upgrades the connection with STARTTLS, the
+	// sending fails if the server doesn't support it.
+	EmailSecurityStartTLS EmailSecurity = "starttls"
+	// EmailSecurityTLS connects with TLS, usually to the port 465.
+	EmailSecurityTLS EmailSecurity = "tls"
+	// EmailSecurityNone doesn't secure the connection, it should only be used
+	// for the local servers.
+	EmailSecurityNone EmailSecurity = "none"
+)
+
+// defaultSubject is the template of the email subject used if none is set.
+const defaultSubject = "[extdash] {{.Summary}}"
+
+// defaultBody is the template of the email body used if none is set.
+const defaultBody = `{{.Summary}}
+
+Extension: {{.Name}}
+Store: {{.Store}}
+ID: {{.AppID}}
+{{- if .State}}
+State: {{if .PreviousState}}{{.PreviousState}} -> {{end}}{{.State}}{{end}}
+{{- if .Version}}
+Version: {{.Version}}{{end}}
+{{- if .PendingVersion}}
+Pending version: {{.PendingVersion}}{{end}}
+{{- if .Message}}
+
+{{.Message}}{{end}}
+`
+
+// Email sends the events by email over SMTP.
+type Email struct {
+	// Addr is the address of the SMTP server, e.g. smtp.example.org:587.
+	Addr string
+	// Username and Password are used for PLAIN authentication, the client
+	// isn't authenticated if Username is empty.
+	Username string
+	Password string
+	From     string
+	To       []string
+	// Subject is the template of the subject, "[extdash] " and the event
+	// summary is used if nil.
+	Subject *template.Template
+	// Body is the template of the plain text body, the event summary and
+	// details are used if nil.
+	Body *template.Template
+	// Security is the way the connection is secured, EmailSecurityStartTLS is
+	// used if empty.
+	Security EmailSecurity
+	// TLSConfig is used for TLS connections, the server name is set from
+	// Addr if nil.
+	TLSConfig *tls.Config
+	// Now returns the current time, time.Now is used if nil.
+	Now func() time.Time
+}
+
+// type check
+var _ Notifier = (*Email)(nil)
+
+// message returns the email message with the headers.
+func (e *Email) message(event *Event) (msg []byte, err error) {
+	subjectTmpl, bodyTmpl := e.Subject, e.Body
+	if subjectTmpl == nil {
+		subjectTmpl = template.Must(ParseTemplate("subject", defaultSubject))
+	}
+
+	if bodyTmpl == nil {
+		bodyTmpl = template.Must(ParseTemplate("body", defaultBody))
+	}
+
+	subject, err := render(subjectTmpl, event)
+	if err != nil {
+		return nil, fmt.Errorf("creating subject: %w", err)
+	}
+
+	body, err := render(bodyTmpl, event)
+	if err != nil {
+		return nil, fmt.Errorf("creating body: %w", err)
+	}
+
+	now := time.Now
+	if e.Now != nil {
+		now = e.Now
+	}
+
+	b := &bytes.Buffer{}
+	fmt.Fprintf(b, "From: %s\r\n", e.From)
+	fmt.Fprintf(b, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(string(subject))))
+	fmt.Fprintf(b, "Date: %s\r\n", now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n")
+	b.WriteString("\r\n")
+
+	// normalize line endings as required by RFC 5321
+	body = bytes.ReplaceAll(body, []byte("\r\n"), []byte("\n"))
+	b.Write(bytes.ReplaceAll(body, []byte("\n"), []byte("\r\n")))
+
+	return b.Bytes(), nil
+}
+
+// tlsConfig returns the TLS configuration for the host.
+func (e *Email) tlsConfig(host string) (conf *tls.Config) {
+	if e.TLSConfig != nil {
+		return e.TLSConfig
+	}
+
+	return &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+}
+
+// dial connects to the SMTP server and secures the connection.
+func (e *Email) dial(ctx context.Context, host string) (client *smtp.Client, err error) {
+	dialer := &net.Dialer{Timeout: requestTimeout}
+
+	conn, err := dialer.DialContext(ctx, "tcp", e.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("connecting: %w", err)
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(requestTimeout)
+	}
+
+	err = conn.SetDeadline(deadline)
+	if err != nil {
+		return nil, errors.WithDeferred(fmt.Errorf("setting deadline: %w", err), conn.Close())
+	}
+
+	security := e.Security
+	if security == "" {
+		security = EmailSecurityStartTLS
+	}
+
+	if security == EmailSecurityTLS {
+		conn = tls.Client(conn, e.tlsConfig(host))
+	}
+
+	client, err = smtp.NewClient(conn, host)
+	if err != nil {
+		return nil, errors.WithDeferred(fmt.Errorf("creating client: %w", err), conn.Close())
+	}
+
+	switch security {
+	case EmailSecurityStartTLS:
+		if ok, _ = client.Extension("STARTTLS"); !ok {
+			return nil, errors.WithDeferred(fmt.Errorf("server doesn't support STARTTLS"), client.Close())
+		}
+
+		err = client.StartTLS(e.tlsConfig(host))
+		if err != nil {
+			return nil, errors.WithDeferred(fmt.Errorf("starting tls: %w", err), client.Close())
+		}
+	case EmailSecurityTLS, EmailSecurityNone:
+		// go on
+	default:
+		return nil, errors.WithDeferred(fmt.Errorf("unknown security %q", security), client.Close())
+	}
+
+	return client, nil
+}
+
+// Notify implements the Notifier interface for *Email.
+func (e *Email) Notify(ctx context.Context, event *Event) (err error) {
+	if len(e.To) == 0 {
+		return fmt.Errorf("no recipients")
+	}
+
+	msg, err := e.message(event)
+	if err != nil {
+		return fmt.Errorf("creating message: %w", err)
+	}
+
+	host, _, err := net.SplitHostPort(e.Addr)
+	if err != nil {
+		return fmt.Errorf("parsing address: %w", err)
+	}
+
+	client, err := e.dial(ctx, host)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// the connection is closed by Quit on success
+		if err != nil {
+			err = errors.WithDeferred(err, client.Close())
+		}
+	}()
+
+	if e.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", e.Username, e.Password, host))
+		if err != nil {
+			return fmt.Errorf("authenticating: %w", err)
+		}
+	}
+
+	err = client.Mail(e.From)
+	if err != nil {
+		return fmt.Errorf("setting sender: %w", err)
+	}
+
+	for _, to := range e.To {
+		err = client.Rcpt(to)
+		if err != nil {
+			return fmt.Errorf("adding recipient %q: %w", to, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("starting data: %w", err)
+	}
+
+	_, err = w.Write(msg)
+	if err != nil {
+		return errors.WithDeferred(fmt.Errorf("writing message: %w", err), w.Close())
+	}
+
+	err = w.Close()
+	if err != nil {
+		return fmt.Errorf("sending message: %w", err)
+	}
+
+	err = client.Quit()
+	if err != nil {
+		return fmt.Errorf("quitting: %w", err)
+	}
+
+	return nil
+}
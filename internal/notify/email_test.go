@@ -0,0 +1,265 @@
+package notify_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/maximtop/extdash/internal/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// smtpMessage is the message received by smtpStub.
+type smtpMessage struct {
+	auth     string
+	from     string
+	to       []string
+	data     string
+	startTLS bool
+}
+
+// smtpStub is the minimal SMTP server, which records the received messages.
+type smtpStub struct {
+	listener  net.Listener
+	tlsConfig *tls.Config
+
+	mu       sync.Mutex
+	messages []*smtpMessage
+}
+
+// newSMTPStub starts the SMTP server, STARTTLS is supported if tlsConfig isn't
+// nil.
+func newSMTPStub(t *testing.T, tlsConfig *tls.Config) (s *smtpStub) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s = &smtpStub{listener: listener, tlsConfig: tlsConfig}
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, acceptErr := listener.Accept()
+			if acceptErr != nil {
+				return
+			}
+
+			go s.serve(conn)
+		}
+	}()
+
+	return s
+}
+
+// received returns the received messages.
+func (s *smtpStub) received() (messages []*smtpMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]*smtpMessage(nil), s.messages...)
+}
+
+// serve handles the SMTP session.
+func (s *smtpStub) serve(conn net.Conn) {
+	defer func() { _ = conn.Close() }()
+
+	msg := &smtpMessage{}
+	text := textproto.NewConn(conn)
+	_ = text.PrintfLine("220 localhost ESMTP")
+
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+
+		cmd, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(cmd) {
+		case "EHLO", "HELO":
+			_ = text.PrintfLine("250-localhost")
+			if s.tlsConfig != nil && !msg.startTLS {
+				_ = text.PrintfLine("250-STARTTLS")
+			}
+			_ = text.PrintfLine("250 AUTH PLAIN")
+		case "STARTTLS":
+			_ = text.PrintfLine("220 ready")
+
+			tlsConn := tls.Server(conn, s.tlsConfig)
+			if tlsConn.Handshake() != nil {
+				return
+			}
+
+			conn = tlsConn
+			text = textproto.NewConn(conn)
+			msg.startTLS = true
+		case "AUTH":
+			data, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(arg, "PLAIN "))
+			msg.auth = string(data)
+			_ = text.PrintfLine("235 ok")
+		case "MAIL":
+			msg.from = arg
+			_ = text.PrintfLine("250 ok")
+		case "RCPT":
+			msg.to = append(msg.to, arg)
+			_ = text.PrintfLine("250 ok")
+		case "DATA":
+			_ = text.PrintfLine("354 go ahead")
+
+			data, readErr := text.ReadDotBytes()
+			if readErr != nil {
+				return
+			}
+
+			msg.data = string(data)
+			_ = text.PrintfLine("250 ok")
+
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+		case "QUIT":
+			_ = text.PrintfLine("221 bye")
+
+			return
+		default:
+			_ = text.PrintfLine("502 not implemented")
+		}
+	}
+}
+
+// newTestTLSConfigs returns the server config with the self-signed
+// certificate for 127.0.0.1 and the client config trusting it.
+func newTestTLSConfigs(t *testing.T) (server, client *tls.Config) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	server = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	client = &tls.Config{
+		RootCAs:    pool,
+		ServerName: "127.0.0.1",
+		MinVersion: tls.VersionTLS12,
+	}
+
+	return server, client
+}
+
+func TestEmail_Notify(t *testing.T) {
+	serverTLS, clientTLS := newTestTLSConfigs(t)
+	server := newSMTPStub(t, serverTLS)
+
+	email := &notify.Email{
+		Addr:      server.listener.Addr().String(),
+		Username:  "user",
+		Password:  "password",
+		From:      "extdash@example.org",
+		To:        []string{"first@example.org", "second@example.org"},
+		TLSConfig: clientTLS,
+		Now:       func() time.Time { return time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC) },
+	}
+
+	err := email.Notify(context.Background(), testEvent)
+	require.NoError(t, err)
+
+	messages := server.received()
+	require.Len(t, messages, 1)
+	msg := messages[0]
+
+	assert.True(t, msg.startTLS)
+	assert.Equal(t, "\x00user\x00password", msg.auth)
+	assert.Equal(t, "FROM:<extdash@example.org>", msg.from)
+	assert.Equal(t, []string{"TO:<first@example.org>", "TO:<second@example.org>"}, msg.to)
+
+	headers, body, ok := strings.Cut(msg.data, "\n\n")
+	require.True(t, ok)
+
+	assert.Contains(t, headers, "To: first@example.org, second@example.org\n")
+	assert.Contains(t, headers, "Subject: [extdash] test (chrome): in_review -> published, version 1.0.1\n")
+	assert.Contains(t, headers, "Date: Sat, 01 Jan 2022 00:00:00 +0000\n")
+	assert.Contains(t, body, "State: in_review -> published\n")
+	assert.Contains(t, body, "Version: 1.0.1\n")
+}
+
+func TestEmail_Notify_template(t *testing.T) {
+	server := newSMTPStub(t, nil)
+
+	subject, err := notify.ParseTemplate("subject", "{{.Extension}} is {{.State}}")
+	require.NoError(t, err)
+
+	body, err := notify.ParseTemplate("body", "Version {{.Version}} is live in {{.Store}}.")
+	require.NoError(t, err)
+
+	email := &notify.Email{
+		Addr:     server.listener.Addr().String(),
+		From:     "extdash@example.org",
+		To:       []string{"team@example.org"},
+		Subject:  subject,
+		Body:     body,
+		Security: notify.EmailSecurityNone,
+	}
+
+	err = email.Notify(context.Background(), testEvent)
+	require.NoError(t, err)
+
+	messages := server.received()
+	require.Len(t, messages, 1)
+	msg := messages[0]
+
+	assert.False(t, msg.startTLS)
+	assert.Empty(t, msg.auth)
+	assert.Contains(t, msg.data, "Subject: test is published\n")
+	assert.True(t, strings.HasSuffix(msg.data, "\n\nVersion 1.0.1 is live in chrome.\n"), msg.data)
+}
+
+func TestEmail_Notify_noStartTLS(t *testing.T) {
+	server := newSMTPStub(t, nil)
+
+	email := &notify.Email{
+		Addr: server.listener.Addr().String(),
+		From: "extdash@example.org",
+		To:   []string{"team@example.org"},
+	}
+
+	err := email.Notify(context.Background(), testEvent)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "STARTTLS")
+
+	assert.Empty(t, server.received())
+}
@@ -0,0 +1,170 @@
+// Package notify contains the events about the extensions and the notifiers
+// delivering them.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// EventType is the type of the event.
+type EventType string
+
+// Event types.
+const (
+	// EventStatusChanged is sent when the state or the versions of the
+	// extension in the store change.
+	EventStatusChanged EventType = "status_changed"
+	// EventReleaseSucceeded is sent when the release command succeeds.
+	EventReleaseSucceeded EventType = "release_succeeded"
+	// EventReleaseFailed is sent when the release command fails.
+	EventReleaseFailed EventType = "release_failed"
+	// EventAlert is sent when the condition of the alert rule lasts longer
+	// than its threshold.
+	EventAlert EventType = "alert"
+)
+
+// Event describes the event about the extension in the store.
+type Event struct {
+	Time      time.Time `json:"time"`
+	Type      EventType `json:"type"`
+	Extension string    `json:"extension,omitempty"`
+	Store     string    `json:"store"`
+	AppID     string    `json:"app_id"`
+	// Version is the published version of the extension.
+	Version string `json:"version,omitempty"`
+	// PendingVersion is the version of the extension which isn't published
+	// yet, e.g. awaits review.
+	PendingVersion string `json:"pending_version,omitempty"`
+	PreviousState  string `json:"previous_state,omitempty"`
+	State          string `json:"state,omitempty"`
+	// Operation is the release operation, e.g. "publish", for the release
+	// events.
+	Operation string `json:"operation,omitempty"`
+	// Rule is the alert rule, e.g. "review", for the alert events.
+	Rule string `json:"rule,omitempty"`
+	// Message contains the details, e.g. the error of the failed release.
+	Message string `json:"message,omitempty"`
+}
+
+// Name returns the name of the extension, or its ID if the name is unknown.
+func (e *Event) Name() (name string) {
+	if e.Extension != "" {
+		return e.Extension
+	}
+
+	return e.AppID
+}
+
+// Summary returns the one-line human-readable description of the event.
+func (e *Event) Summary() (summary string) {
+	b := &strings.Builder{}
+
+	switch e.Type {
+	case EventStatusChanged:
+		fmt.Fprintf(b, "%s (%s): ", e.Name(), e.Store)
+		if e.PreviousState != "" && e.PreviousState != e.State {
+			fmt.Fprintf(b, "%s -> ", e.PreviousState)
+		}
+		b.WriteString(e.State)
+	case EventReleaseSucceeded:
+		fmt.Fprintf(b, "%s (%s): %s succeeded", e.Name(), e.Store, e.Operation)
+	case EventReleaseFailed:
+		fmt.Fprintf(b, "%s (%s): %s failed", e.Name(), e.Store, e.Operation)
+	case EventAlert:
+		fmt.Fprintf(b, "%s (%s): %s alert", e.Name(), e.Store, e.Rule)
+	default:
+		fmt.Fprintf(b, "%s (%s): %s", e.Name(), e.Store, e.Type)
+	}
+
+	if e.Version != "" {
+		fmt.Fprintf(b, ", version %s", e.Version)
+	}
+
+	if e.PendingVersion != "" {
+		fmt.Fprintf(b, ", pending %s", e.PendingVersion)
+	}
+
+	if e.Message != "" {
+		fmt.Fprintf(b, ": %s", e.Message)
+	}
+
+	return b.String()
+}
+
+// Notifier delivers the events.
+type Notifier interface {
+	Notify(ctx context.Context, event *Event) (err error)
+}
+
+// Multi delivers the events to all notifiers, the errors are joined.
+type Multi []Notifier
+
+// type check
+var _ Notifier = Multi(nil)
+
+// Notify implements the Notifier interface for Multi.
+func (m Multi) Notify(ctx context.Context, event *Event) (err error) {
+	var errs []error
+	for _, n := range m {
+		notifyErr := n.Notify(ctx, event)
+		if notifyErr != nil {
+			errs = append(errs, notifyErr)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.List("notifying", errs...)
+	}
+
+	return nil
+}
+
+// Writer writes the event summaries to the writer, one per line.
+type Writer struct {
+	W io.Writer
+}
+
+// type check
+var _ Notifier = (*Writer)(nil)
+
+// Notify implements the Notifier interface for *Writer.
+func (w *Writer) Notify(_ context.Context, event *Event) (err error) {
+	_, err = fmt.Fprintf(w.W, "%s %s\n", event.Time.Format(time.RFC3339), event.Summary())
+	if err != nil {
+		return fmt.Errorf("writing event: %w", err)
+	}
+
+	return nil
+}
+
+// Filter delivers only the events of the selected types.
+type Filter struct {
+	Notifier Notifier
+	// Types are the types of the events to deliver, all events are
+	// delivered if empty.
+	Types []EventType
+}
+
+// type check
+var _ Notifier = (*Filter)(nil)
+
+// Notify implements the Notifier interface for *Filter.
+func (f *Filter) Notify(ctx context.Context, event *Event) (err error) {
+	if len(f.Types) == 0 {
+		return f.Notifier.Notify(ctx, event)
+	}
+
+	for _, t := range f.Types {
+		if t == event.Type {
+			return f.Notifier.Notify(ctx, event)
+		}
+	}
+
+	return nil
+}
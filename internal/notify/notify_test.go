@@ -0,0 +1,78 @@
+package notify_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maximtop/extdash/internal/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvent_Summary(t *testing.T) {
+	testCases := []struct {
+		name  string
+		event notify.Event
+		want  string
+	}{{
+		name: "status_changed",
+		event: notify.Event{
+			Type:           notify.EventStatusChanged,
+			Extension:      "test",
+			Store:          "firefox",
+			Version:        "1.0.0",
+			PendingVersion: "1.0.1",
+			PreviousState:  "published",
+			State:          "in_review",
+		},
+		want: "test (firefox): published -> in_review, version 1.0.0, pending 1.0.1",
+	}, {
+		name: "release_failed",
+		event: notify.Event{
+			Type:      notify.EventReleaseFailed,
+			Store:     "chrome",
+			AppID:     "abc",
+			Operation: "publish",
+			Message:   "got code 400",
+		},
+		want: "abc (chrome): publish failed: got code 400",
+	}, {
+		name: "alert",
+		event: notify.Event{
+			Type:      notify.EventAlert,
+			Rule:      "drift",
+			Extension: "test",
+			Store:     "chrome",
+			Version:   "1.0.0",
+			Message:   "version 1.0.1 is published in firefox",
+		},
+		want: "test (chrome): drift alert, version 1.0.0: version 1.0.1 is published in firefox",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, tc.event.Summary())
+		})
+	}
+}
+
+func TestMulti(t *testing.T) {
+	var first, second bytes.Buffer
+
+	n := notify.Multi{&notify.Writer{W: &first}, &notify.Writer{W: &second}}
+
+	err := n.Notify(context.Background(), &notify.Event{
+		Time:      time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		Type:      notify.EventReleaseSucceeded,
+		Store:     "edge",
+		AppID:     "abc",
+		Operation: "publish",
+	})
+	require.NoError(t, err)
+
+	want := "2022-01-01T00:00:00Z abc (edge): publish succeeded\n"
+	assert.Equal(t, want, first.String())
+	assert.Equal(t, want, second.String())
+}
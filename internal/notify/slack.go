@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"text/template"
+)
+
+// Slack posts the events to the Slack incoming webhook.
+type Slack struct {
+	// Client is used to send the requests, the client with the default
+	// timeout is used if nil.
+	Client *http.Client
+	// URL is the address of the incoming webhook.
+	URL string
+	// Template is the template of the message text, the event summary is
+	// used if nil.
+	Template *template.Template
+}
+
+// type check
+var _ Notifier = (*Slack)(nil)
+
+// slackMessage describes the body of the incoming webhook request.
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// Notify implements the Notifier interface for *Slack.
+func (s *Slack) Notify(ctx context.Context, event *Event) (err error) {
+	text := event.Summary()
+	if s.Template != nil {
+		var data []byte
+		data, err = render(s.Template, event)
+		if err != nil {
+			return fmt.Errorf("creating message: %w", err)
+		}
+
+		text = string(data)
+	}
+
+	body, err := json.Marshal(slackMessage{Text: text})
+	if err != nil {
+		return fmt.Errorf("marshaling message: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	err = post(ctx, s.Client, req)
+	if err != nil {
+		return fmt.Errorf("posting to slack: %w", err)
+	}
+
+	return nil
+}
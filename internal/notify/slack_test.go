@@ -0,0 +1,46 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/maximtop/extdash/internal/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlack_Notify(t *testing.T) {
+	var message struct {
+		Text string `json:"text"`
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&message))
+	}))
+	defer server.Close()
+
+	t.Run("summary", func(t *testing.T) {
+		slack := &notify.Slack{URL: server.URL}
+
+		err := slack.Notify(context.Background(), testEvent)
+		require.NoError(t, err)
+
+		assert.Equal(t, "test (chrome): in_review -> published, version 1.0.1", message.Text)
+	})
+
+	t.Run("template", func(t *testing.T) {
+		tmpl, err := notify.ParseTemplate("test", ":rocket: *{{.Extension}}* {{.Version}} is {{.State}} in {{.Store}}")
+		require.NoError(t, err)
+
+		slack := &notify.Slack{URL: server.URL, Template: tmpl}
+
+		err = slack.Notify(context.Background(), testEvent)
+		require.NoError(t, err)
+
+		assert.Equal(t, ":rocket: *test* 1.0.1 is published in chrome", message.Text)
+	})
+}
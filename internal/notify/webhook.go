@@ -0,0 +1,161 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"text/template"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// requestTimeout is the timeout of the notification request.
+const requestTimeout = 30 * time.Second
+
+// maxReadLimit limits the size of the response body read for the error
+// message.
+const maxReadLimit = 64 * 1024
+
+// DefaultSignatureHeader is the header with the HMAC signature of the webhook
+// payload.
+const DefaultSignatureHeader = "X-Extdash-Signature"
+
+// TimestampHeader is the header with the Unix time of the webhook request,
+// which is signed along with the payload.
+const TimestampHeader = "X-Extdash-Timestamp"
+
+// templateFuncs contains the functions available in the templates.
+var templateFuncs = template.FuncMap{
+	// json returns the JSON representation of the value, e.g. to put strings
+	// to the JSON payload.
+	"json": func(v any) (s string, err error) {
+		data, err := json.Marshal(v)
+
+		return string(data), err
+	},
+}
+
+// ParseTemplate parses the template of the notification, the event is passed
+// as the data.  In addition to the standard functions, json function returns
+// the JSON representation of the value.
+func ParseTemplate(name, text string) (tmpl *template.Template, err error) {
+	return template.New(name).Funcs(templateFuncs).Parse(text)
+}
+
+// render executes the template with the event.
+func render(tmpl *template.Template, event *Event) (data []byte, err error) {
+	b := &bytes.Buffer{}
+
+	err = tmpl.Execute(b, event)
+	if err != nil {
+		return nil, fmt.Errorf("executing template: %w", err)
+	}
+
+	return b.Bytes(), nil
+}
+
+// Sign returns the signature of the payload sent at the time, which is the hex
+// encoded HMAC-SHA256 of "<timestamp>.<payload>" with the "sha256=" prefix.
+func Sign(secret []byte, timestamp int64, payload []byte) (signature string) {
+	mac := hmac.New(sha256.New, secret)
+	_, _ = fmt.Fprintf(mac, "%d.", timestamp)
+	_, _ = mac.Write(payload)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// post sends the payload to the URL and checks the response code.
+func post(ctx context.Context, client *http.Client, req *http.Request) (err error) {
+	if client == nil {
+		client = &http.Client{Timeout: requestTimeout}
+	}
+
+	res, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("sending request: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, res.Body.Close()) }()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(res.Body, maxReadLimit))
+
+		return fmt.Errorf("got code %d, body: %q", res.StatusCode, body)
+	}
+
+	return nil
+}
+
+// Webhook sends the events as JSON to the URL.  If Secret is set, the payload
+// is signed, see Sign.
+type Webhook struct {
+	// Client is used to send the requests, the client with the default
+	// timeout is used if nil.
+	Client *http.Client
+	URL    string
+	// Secret is the key of the HMAC signature, the payload isn't signed if
+	// empty.
+	Secret []byte
+	// SignatureHeader is the header with the signature,
+	// DefaultSignatureHeader is used if empty.
+	SignatureHeader string
+	// Template is the template of the payload, the event is marshaled to
+	// JSON if nil.
+	Template *template.Template
+	// Now returns the current time, time.Now is used if nil.
+	Now func() time.Time
+}
+
+// type check
+var _ Notifier = (*Webhook)(nil)
+
+// Notify implements the Notifier interface for *Webhook.
+func (w *Webhook) Notify(ctx context.Context, event *Event) (err error) {
+	var payload []byte
+	if w.Template != nil {
+		payload, err = render(w.Template, event)
+	} else {
+		payload, err = json.Marshal(event)
+	}
+	if err != nil {
+		return fmt.Errorf("creating payload: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.URL, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("creating request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if len(w.Secret) > 0 {
+		now := time.Now
+		if w.Now != nil {
+			now = w.Now
+		}
+
+		timestamp := now().Unix()
+
+		header := w.SignatureHeader
+		if header == "" {
+			header = DefaultSignatureHeader
+		}
+
+		req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+		req.Header.Set(header, Sign(w.Secret, timestamp, payload))
+	}
+
+	err = post(ctx, w.Client, req)
+	if err != nil {
+		return fmt.Errorf("posting webhook: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package notify_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/maximtop/extdash/internal/notify"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testEvent = &notify.Event{
+	Time:          time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+	Type:          notify.EventStatusChanged,
+	Extension:     "test",
+	Store:         "chrome",
+	AppID:         "abc",
+	Version:       "1.0.1",
+	PreviousState: "in_review",
+	State:         "published",
+}
+
+func TestWebhook_Notify(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Date(2022, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	var payload []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		payload, err = io.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		timestamp, err := strconv.ParseInt(r.Header.Get(notify.TimestampHeader), 10, 64)
+		require.NoError(t, err)
+		assert.Equal(t, now.Unix(), timestamp)
+
+		assert.Equal(t, notify.Sign(secret, timestamp, payload), r.Header.Get(notify.DefaultSignatureHeader))
+	}))
+	defer server.Close()
+
+	t.Run("default_payload", func(t *testing.T) {
+		webhook := &notify.Webhook{
+			URL:    server.URL,
+			Secret: secret,
+			Now:    func() time.Time { return now },
+		}
+
+		err := webhook.Notify(context.Background(), testEvent)
+		require.NoError(t, err)
+
+		event := &notify.Event{}
+		require.NoError(t, json.Unmarshal(payload, event))
+		assert.Equal(t, testEvent, event)
+	})
+
+	t.Run("template", func(t *testing.T) {
+		tmpl, err := notify.ParseTemplate("test", `{"message": {{json .Summary}}, "state": {{json .State}}}`)
+		require.NoError(t, err)
+
+		webhook := &notify.Webhook{
+			URL:      server.URL,
+			Secret:   secret,
+			Template: tmpl,
+			Now:      func() time.Time { return now },
+		}
+
+		err = webhook.Notify(context.Background(), testEvent)
+		require.NoError(t, err)
+
+		assert.JSONEq(t, `{
+			"message": "test (chrome): in_review -> published, version 1.0.1",
+			"state": "published"
+		}`, string(payload))
+	})
+}
+
+func TestWebhook_Notify_error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "test error", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	webhook := &notify.Webhook{URL: server.URL}
+
+	err := webhook.Notify(context.Background(), testEvent)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "got code 500")
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1.{}' | openssl dgst -sha256 -hmac secret
+	want := "sha256=1122767b193110cfec322b6f199b599edbf608ed087f2d27afb0b97d99523908"
+
+	assert.Equal(t, want, notify.Sign([]byte("secret"), 1, []byte("{}")))
+}
@@ -0,0 +1,244 @@
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/maximtop/extdash/internal/firefox"
+)
+
+// requestTimeout is the timeout of the listing page request.
+const requestTimeout = 30 * time.Second
+
+// maxReadLimit limits the size of the listing page.
+const maxReadLimit = 5 * 1024 * 1024
+
+// FirefoxCollector retrieves the statistics from the AMO API.
+type FirefoxCollector struct {
+	Store *firefox.Store
+}
+
+// type check
+var _ Collector = (*FirefoxCollector)(nil)
+
+// Collect implements the Collector interface for *FirefoxCollector.
+func (c *FirefoxCollector) Collect(appID string) (sample *Sample, err error) {
+	addon, err := c.Store.Addon(appID)
+	if err != nil {
+		return nil, fmt.Errorf("getting add-on: %w", err)
+	}
+
+	sample = &Sample{
+		Store:           "firefox",
+		AppID:           appID,
+		Users:           int64(addon.AverageDailyUsers),
+		WeeklyDownloads: int64(addon.WeeklyDownloads),
+		Rating:          addon.Ratings.Average,
+		RatingCount:     int64(addon.Ratings.Count),
+		ReviewCount:     int64(addon.Ratings.TextCount),
+	}
+
+	if addon.CurrentVersion != nil {
+		sample.Version = addon.CurrentVersion.Version
+	}
+
+	return sample, nil
+}
+
+// get retrieves the page and returns its body.
+func get(client *http.Client, pageURL string) (body []byte, err error) {
+	if client == nil {
+		client = &http.Client{Timeout: requestTimeout}
+	}
+
+	req, err := http.NewRequest(http.MethodGet, pageURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+
+	// the listing pages are localized, the english version is parsed
+	req.Header.Set("Accept-Language", "en-US,en;q=0.9")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("sending request: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, res.Body.Close()) }()
+
+	body, err = io.ReadAll(io.LimitReader(res.Body, maxReadLimit))
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("got code %d", res.StatusCode)
+	}
+
+	return body, nil
+}
+
+// ChromeListingURL is the address of the Chrome Web Store site.
+const ChromeListingURL = "https://chromewebstore.google.com"
+
+// Patterns of the metrics on the Chrome Web Store listing page.
+var (
+	chromeUsersRe       = regexp.MustCompile(`([\d,]+)\+?\s+users`)
+	chromeRatingRe      = regexp.MustCompile(`([\d.]+) out of 5`)
+	chromeRatingCountRe = regexp.MustCompile(`([\d.,]+[KM]?)\s+ratings?\b`)
+	chromeVersionRe     = regexp.MustCompile(`Version</div>\s*<div[^>]*>([\w.]+)<`)
+)
+
+// ChromeCollector retrieves the statistics by parsing the public listing page
+// of the item, since the API doesn't provide them.
+type ChromeCollector struct {
+	// Client is used to retrieve the pages, the client with the default
+	// timeout is used if nil.
+	Client *http.Client
+	// URL is the address of the store site, ChromeListingURL is used if nil.
+	URL *url.URL
+}
+
+// type check
+var _ Collector = (*ChromeCollector)(nil)
+
+// Collect implements the Collector interface for *ChromeCollector.
+func (c *ChromeCollector) Collect(appID string) (sample *Sample, err error) {
+	baseURL := c.URL
+	if baseURL == nil {
+		baseURL, _ = url.Parse(ChromeListingURL)
+	}
+
+	page, err := get(c.Client, baseURL.JoinPath("detail", appID).String())
+	if err != nil {
+		return nil, fmt.Errorf("getting listing page: %w", err)
+	}
+
+	return ParseChromeListing(appID, page)
+}
+
+// ParseChromeListing parses the statistics from the Chrome Web Store listing
+// page.  It returns an error if the number of users isn't found, since the page
+// layout has likely changed.
+func ParseChromeListing(appID string, page []byte) (sample *Sample, err error) {
+	sample = &Sample{
+		Store: "chrome",
+		AppID: appID,
+	}
+
+	match := chromeUsersRe.FindSubmatch(page)
+	if match == nil {
+		return nil, fmt.Errorf("number of users is not found on the listing page")
+	}
+
+	sample.Users, err = parseCount(string(match[1]))
+	if err != nil {
+		return nil, fmt.Errorf("parsing users: %w", err)
+	}
+
+	if match = chromeRatingRe.FindSubmatch(page); match != nil {
+		sample.Rating, err = strconv.ParseFloat(string(match[1]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("parsing rating: %w", err)
+		}
+	}
+
+	if match = chromeRatingCountRe.FindSubmatch(page); match != nil {
+		sample.RatingCount, err = parseCount(string(match[1]))
+		if err != nil {
+			return nil, fmt.Errorf("parsing rating count: %w", err)
+		}
+	}
+
+	if match = chromeVersionRe.FindSubmatch(page); match != nil {
+		sample.Version = string(match[1])
+	}
+
+	return sample, nil
+}
+
+// parseCount parses the number like "1,234", "12.3K" or "1M".
+func parseCount(s string) (n int64, err error) {
+	s = strings.ReplaceAll(s, ",", "")
+
+	multiplier := 1.0
+	switch {
+	case strings.HasSuffix(s, "K"):
+		multiplier = 1e3
+		s = strings.TrimSuffix(s, "K")
+	case strings.HasSuffix(s, "M"):
+		multiplier = 1e6
+		s = strings.TrimSuffix(s, "M")
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	return int64(f * multiplier), nil
+}
+
+// EdgeListingURL is the address of the Edge Add-ons site.
+const EdgeListingURL = "https://microsoftedge.microsoft.com"
+
+// edgeProductDetails describes the listing data of the Edge Add-ons site.
+type edgeProductDetails struct {
+	Name               string  `json:"name"`
+	Version            string  `json:"version"`
+	ActiveInstallCount int64   `json:"activeInstallCount"`
+	AverageRating      float64 `json:"averageRating"`
+	RatingCount        int64   `json:"ratingCount"`
+}
+
+// EdgeCollector retrieves the statistics from the listing data, which the Edge
+// Add-ons site shows on the listing page, since the API doesn't provide them.
+// The data is found by the CRX ID of the extension, which differs from the
+// product ID used by the API.
+type EdgeCollector struct {
+	// Client is used to retrieve the data, the client with the default
+	// timeout is used if nil.
+	Client *http.Client
+	// URL is the address of the site, EdgeListingURL is used if nil.
+	URL *url.URL
+}
+
+// type check
+var _ Collector = (*EdgeCollector)(nil)
+
+// Collect implements the Collector interface for *EdgeCollector.  appID is the
+// CRX ID of the extension.
+func (c *EdgeCollector) Collect(appID string) (sample *Sample, err error) {
+	baseURL := c.URL
+	if baseURL == nil {
+		baseURL, _ = url.Parse(EdgeListingURL)
+	}
+
+	body, err := get(c.Client, baseURL.JoinPath("addons/getproductdetailsbycrxid", appID).String())
+	if err != nil {
+		return nil, fmt.Errorf("getting listing data: %w", err)
+	}
+
+	details := &edgeProductDetails{}
+
+	err = json.Unmarshal(body, details)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshaling listing data: %w", err)
+	}
+
+	return &Sample{
+		Store:       "edge",
+		AppID:       appID,
+		Users:       details.ActiveInstallCount,
+		Rating:      details.AverageRating,
+		RatingCount: details.RatingCount,
+		Version:     details.Version,
+	}, nil
+}
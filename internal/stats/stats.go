@@ -0,0 +1,217 @@
+// Package stats contains the collectors of the extension statistics from the
+// stores and the storage of the collected samples.
+package stats
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+)
+
+// Sample contains the statistics of the extension in the store at some
+// moment.  The metrics, which aren't available in the store, are zero.
+type Sample struct {
+	Time      time.Time `json:"time"`
+	Extension string    `json:"extension,omitempty"`
+	Store     string    `json:"store"`
+	AppID     string    `json:"app_id"`
+	// Users is the number of users, AMO reports the average daily users.
+	Users int64 `json:"users"`
+	// WeeklyDownloads is the number of downloads in the last week.
+	WeeklyDownloads int64 `json:"weekly_downloads,omitempty"`
+	// Rating is the average rating from 0 to 5.
+	Rating      float64 `json:"rating"`
+	RatingCount int64   `json:"rating_count"`
+	// ReviewCount is the number of ratings with the text review.
+	ReviewCount int64 `json:"review_count,omitempty"`
+	// Version is the published version, if the store reports it.
+	Version string `json:"version,omitempty"`
+}
+
+// Collector retrieves the statistics of the extension from the store.
+type Collector interface {
+	Collect(appID string) (sample *Sample, err error)
+}
+
+// Target is the extension in the store to collect the statistics of.
+type Target struct {
+	Extension string
+	Store     string
+	AppID     string
+	Collector Collector
+}
+
+// Collect collects the statistics of the targets and sets the time of the
+// samples to now.  The errors of the targets don't stop the collection, they
+// are returned joined along with the collected samples.
+func Collect(targets []*Target, now time.Time) (samples []*Sample, err error) {
+	var errs []error
+	for _, t := range targets {
+		sample, collectErr := t.Collector.Collect(t.AppID)
+		if collectErr != nil {
+			errs = append(errs, fmt.Errorf("%s in %s: %w", t.Extension, t.Store, collectErr))
+
+			continue
+		}
+
+		sample.Time = now
+		sample.Extension = t.Extension
+		samples = append(samples, sample)
+	}
+
+	if len(errs) > 0 {
+		return samples, errors.List("collecting stats", errs...)
+	}
+
+	return samples, nil
+}
+
+// File stores the samples in the JSON Lines file.
+type File struct {
+	Path string
+}
+
+// Append appends the samples to the file, which is created if it doesn't
+// exist.
+func (f *File) Append(samples []*Sample) (err error) {
+	err = os.MkdirAll(filepath.Dir(f.Path), 0o700)
+	if err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Clean(f.Path), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening samples: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, file.Close()) }()
+
+	encoder := json.NewEncoder(file)
+	for _, sample := range samples {
+		err = encoder.Encode(sample)
+		if err != nil {
+			return fmt.Errorf("writing sample: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Filter selects the samples.
+type Filter struct {
+	// Extension selects the samples of the extension, all if empty.
+	Extension string
+	// Store selects the samples from the store, all if empty.
+	Store string
+	// Since selects the samples collected at or after the time, all if zero.
+	Since time.Time
+}
+
+// Match returns true if the sample is selected by the filter.
+func (f *Filter) Match(sample *Sample) (ok bool) {
+	return (f.Extension == "" || f.Extension == sample.Extension) &&
+		(f.Store == "" || f.Store == sample.Store) &&
+		!sample.Time.Before(f.Since)
+}
+
+// Read returns the samples selected by the filter in the order they were
+// appended.  It returns nil if the file doesn't exist.
+func (f *File) Read(filter *Filter) (samples []*Sample, err error) {
+	file, err := os.Open(filepath.Clean(f.Path))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("opening samples: %w", err)
+	}
+	defer func() { err = errors.WithDeferred(err, file.Close()) }()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		sample := &Sample{}
+
+		err = json.Unmarshal(scanner.Bytes(), sample)
+		if err != nil {
+			return nil, fmt.Errorf("parsing line %d: %w", line, err)
+		}
+
+		if filter == nil || filter.Match(sample) {
+			samples = append(samples, sample)
+		}
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("reading samples: %w", err)
+	}
+
+	return samples, nil
+}
+
+// csvHeader is the header of the CSV export.
+var csvHeader = []string{
+	"time",
+	"extension",
+	"store",
+	"app_id",
+	"version",
+	"users",
+	"weekly_downloads",
+	"rating",
+	"rating_count",
+	"review_count",
+}
+
+// WriteCSV writes the samples in the CSV format with the header.
+func WriteCSV(w io.Writer, samples []*Sample) (err error) {
+	csvWriter := csv.NewWriter(w)
+
+	err = csvWriter.Write(csvHeader)
+	if err != nil {
+		return fmt.Errorf("writing header: %w", err)
+	}
+
+	for _, s := range samples {
+		err = csvWriter.Write([]string{
+			s.Time.UTC().Format(time.RFC3339),
+			s.Extension,
+			s.Store,
+			s.AppID,
+			s.Version,
+			strconv.FormatInt(s.Users, 10),
+			strconv.FormatInt(s.WeeklyDownloads, 10),
+			strconv.FormatFloat(s.Rating, 'f', -1, 64),
+			strconv.FormatInt(s.RatingCount, 10),
+			strconv.FormatInt(s.ReviewCount, 10),
+		})
+		if err != nil {
+			return fmt.Errorf("writing sample: %w", err)
+		}
+	}
+
+	csvWriter.Flush()
+
+	return csvWriter.Error()
+}
+
+// WriteJSON writes the samples as the JSON array.
+func WriteJSON(w io.Writer, samples []*Sample) (err error) {
+	if samples == nil {
+		samples = []*Sample{}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(samples)
+}
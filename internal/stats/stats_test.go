@@ -0,0 +1,215 @@
+package stats_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maximtop/extdash/internal/firefox"
+	"github.com/maximtop/extdash/internal/stats"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer returns the server responding with body to the path and its
+// URL.
+func newTestServer(t *testing.T, path, body string) (u *url.URL) {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	return u
+}
+
+func TestFirefoxCollector_Collect(t *testing.T) {
+	storeURL := newTestServer(t, "/api/v5/addons/addon/test/", `{
+		"current_version": {"version": "1.2.3"},
+		"average_daily_users": 1500,
+		"weekly_downloads": 200,
+		"ratings": {"average": 4.5, "count": 120, "text_count": 40}
+	}`)
+
+	client := firefox.NewClient(firefox.ClientConfig{ClientID: "id", ClientSecret: "secret"})
+	collector := &stats.FirefoxCollector{Store: &firefox.Store{Client: &client, URL: storeURL}}
+
+	sample, err := collector.Collect("test")
+	require.NoError(t, err)
+
+	assert.Equal(t, &stats.Sample{
+		Store:           "firefox",
+		AppID:           "test",
+		Users:           1500,
+		WeeklyDownloads: 200,
+		Rating:          4.5,
+		RatingCount:     120,
+		ReviewCount:     40,
+		Version:         "1.2.3",
+	}, sample)
+}
+
+func TestParseChromeListing(t *testing.T) {
+	testCases := []struct {
+		name            string
+		page            string
+		wantUsers       int64
+		wantRating      float64
+		wantRatingCount int64
+		wantVersion     string
+		wantErr         bool
+	}{{
+		name: "full",
+		page: `<span>4.7 out of 5 stars</span><a>12.3K ratings</a>` +
+			`<div>1,000,000+ users</div><div>Version</div><div class="v">4.1.52</div>`,
+		wantUsers:       1000000,
+		wantRating:      4.7,
+		wantRatingCount: 12300,
+		wantVersion:     "4.1.52",
+	}, {
+		name:            "single rating",
+		page:            `<span>5 out of 5</span><a>1 rating</a><div>35 users</div>`,
+		wantUsers:       35,
+		wantRating:      5,
+		wantRatingCount: 1,
+	}, {
+		name:      "no ratings",
+		page:      `<div>2 users</div>`,
+		wantUsers: 2,
+	}, {
+		name:    "unknown layout",
+		page:    `<html></html>`,
+		wantErr: true,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sample, err := stats.ParseChromeListing("test", []byte(tc.page))
+			if tc.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			assert.Equal(t, "chrome", sample.Store)
+			assert.Equal(t, tc.wantUsers, sample.Users)
+			assert.Equal(t, tc.wantRating, sample.Rating)
+			assert.Equal(t, tc.wantRatingCount, sample.RatingCount)
+			assert.Equal(t, tc.wantVersion, sample.Version)
+		})
+	}
+}
+
+func TestChromeCollector_Collect(t *testing.T) {
+	listingURL := newTestServer(t, "/detail/test", `<div>10 users</div>`)
+
+	collector := &stats.ChromeCollector{URL: listingURL}
+
+	sample, err := collector.Collect("test")
+	require.NoError(t, err)
+
+	assert.Equal(t, int64(10), sample.Users)
+
+	_, err = collector.Collect("unknown")
+	require.Error(t, err)
+}
+
+func TestEdgeCollector_Collect(t *testing.T) {
+	listingURL := newTestServer(t, "/addons/getproductdetailsbycrxid/test", `{
+		"name": "Test",
+		"version": "2.0.0",
+		"activeInstallCount": 3000,
+		"averageRating": 4.2,
+		"ratingCount": 15
+	}`)
+
+	collector := &stats.EdgeCollector{URL: listingURL}
+
+	sample, err := collector.Collect("test")
+	require.NoError(t, err)
+
+	assert.Equal(t, &stats.Sample{
+		Store:       "edge",
+		AppID:       "test",
+		Users:       3000,
+		Rating:      4.2,
+		RatingCount: 15,
+		Version:     "2.0.0",
+	}, sample)
+}
+
+func TestFile(t *testing.T) {
+	file := &stats.File{Path: filepath.Join(t.TempDir(), "stats", "samples.jsonl")}
+
+	samples, err := file.Read(nil)
+	require.NoError(t, err)
+	assert.Empty(t, samples)
+
+	first := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	second := first.Add(24 * time.Hour)
+
+	err = file.Append([]*stats.Sample{
+		{Time: first, Extension: "test", Store: "chrome", AppID: "a", Users: 1},
+		{Time: first, Extension: "test", Store: "firefox", AppID: "b", Users: 2},
+	})
+	require.NoError(t, err)
+
+	err = file.Append([]*stats.Sample{
+		{Time: second, Extension: "test", Store: "chrome", AppID: "a", Users: 3},
+	})
+	require.NoError(t, err)
+
+	samples, err = file.Read(nil)
+	require.NoError(t, err)
+	require.Len(t, samples, 3)
+	assert.Equal(t, int64(3), samples[2].Users)
+
+	samples, err = file.Read(&stats.Filter{Store: "chrome", Since: second})
+	require.NoError(t, err)
+	require.Len(t, samples, 1)
+	assert.True(t, second.Equal(samples[0].Time))
+}
+
+func TestWriteCSV(t *testing.T) {
+	b := &bytes.Buffer{}
+
+	err := stats.WriteCSV(b, []*stats.Sample{{
+		Time:        time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		Extension:   "test",
+		Store:       "edge",
+		AppID:       "a",
+		Version:     "1.0.0",
+		Users:       10,
+		Rating:      4.5,
+		RatingCount: 2,
+	}})
+	require.NoError(t, err)
+
+	assert.Equal(t, "time,extension,store,app_id,version,users,weekly_downloads,rating,rating_count,review_count\n"+
+		"2022-01-01T00:00:00Z,test,edge,a,1.0.0,10,0,4.5,2,0\n", b.String())
+}
+
+func TestWriteJSON(t *testing.T) {
+	b := &bytes.Buffer{}
+
+	err := stats.WriteJSON(b, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "[]\n", b.String())
+}
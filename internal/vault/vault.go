@@ -0,0 +1,249 @@
+// Package vault contains functions for working with the passphrase-encrypted
+// credentials file.
+package vault
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// ErrWrongPassphrase is returned when the vault can't be decrypted with the
+// passphrase, or it is corrupted.
+const ErrWrongPassphrase errors.Error = "wrong passphrase or corrupted vault"
+
+// formatVersion is the version of the vault file format.
+const formatVersion = 1
+
+// kdfArgon2id is the name of the key derivation function used for the vault.
+const kdfArgon2id = "argon2id"
+
+// Default parameters of the key derivation function, see the recommendations in
+// RFC 9106.
+const (
+	defaultTime    = 3
+	defaultMemory  = 64 * 1024
+	defaultThreads = 4
+	saltLength     = 16
+)
+
+// Maximum parameters of the key derivation function.  The parameters are read
+// from the file before it's authenticated, so the limits prevent the tampered
+// vault from exhausting the memory or the CPU.
+const (
+	maxTime    = 16
+	maxMemory  = 1024 * 1024
+	maxThreads = 64
+)
+
+// KDFParams contains the parameters of the key derivation function.
+type KDFParams struct {
+	Name string `json:"name"`
+	Salt []byte `json:"salt"`
+	// Time is the number of passes over the memory.
+	Time uint32 `json:"time"`
+	// Memory is the size of the memory in KiB.
+	Memory  uint32 `json:"memory"`
+	Threads uint8  `json:"threads"`
+}
+
+// file describes the structure of the vault file.  The header, i.e. version
+// and KDF parameters, is authenticated as the additional data of the AEAD.
+type file struct {
+	Version    int       `json:"version"`
+	KDF        KDFParams `json:"kdf"`
+	Nonce      []byte    `json:"nonce"`
+	Ciphertext []byte    `json:"ciphertext"`
+}
+
+// additionalData returns the authenticated data of the vault file.
+func (f *file) additionalData() (data []byte, err error) {
+	return json.Marshal(struct {
+		Version int       `json:"version"`
+		KDF     KDFParams `json:"kdf"`
+	}{
+		Version: f.Version,
+		KDF:     f.KDF,
+	})
+}
+
+// deriveKey returns the encryption key derived from the passphrase.
+func deriveKey(passphrase []byte, params KDFParams) (key []byte, err error) {
+	if params.Name != kdfArgon2id {
+		return nil, fmt.Errorf("unsupported kdf %q", params.Name)
+	}
+
+	if params.Time == 0 || params.Memory == 0 || params.Threads == 0 {
+		return nil, fmt.Errorf("invalid kdf parameters")
+	}
+
+	if params.Time > maxTime || params.Memory > maxMemory || params.Threads > maxThreads {
+		return nil, fmt.Errorf(
+			"kdf parameters exceed the limits: time %d, memory %d KiB, threads %d",
+			params.Time,
+			params.Memory,
+			params.Threads,
+		)
+	}
+
+	return argon2.IDKey(passphrase, params.Salt, params.Time, params.Memory, params.Threads, chacha20poly1305.KeySize), nil
+}
+
+// Seal encrypts the secrets with the key derived from the passphrase using
+// XChaCha20-Poly1305.  A new random salt and nonce are generated every time.
+func Seal(secrets map[string]string, passphrase []byte) (data []byte, err error) {
+	f := &file{
+		Version: formatVersion,
+		KDF: KDFParams{
+			Name:    kdfArgon2id,
+			Salt:    make([]byte, saltLength),
+			Time:    defaultTime,
+			Memory:  defaultMemory,
+			Threads: defaultThreads,
+		},
+		Nonce: make([]byte, chacha20poly1305.NonceSizeX),
+	}
+
+	if _, err = rand.Read(f.KDF.Salt); err != nil {
+		return nil, fmt.Errorf("generating salt: %w", err)
+	}
+
+	if _, err = rand.Read(f.Nonce); err != nil {
+		return nil, fmt.Errorf("generating nonce: %w", err)
+	}
+
+	key, err := deriveKey(passphrase, f.KDF)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling secrets: %w", err)
+	}
+
+	additionalData, err := f.additionalData()
+	if err != nil {
+		return nil, fmt.Errorf("marshaling header: %w", err)
+	}
+
+	f.Ciphertext = aead.Seal(nil, f.Nonce, plaintext, additionalData)
+
+	data, err = json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshaling vault: %w", err)
+	}
+
+	return data, nil
+}
+
+// Open decrypts the secrets sealed with the passphrase.  It returns
+// ErrWrongPassphrase if the data can't be authenticated.
+func Open(data, passphrase []byte) (secrets map[string]string, err error) {
+	f := &file{}
+
+	err = json.Unmarshal(data, f)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshaling vault: %w", err)
+	}
+
+	if f.Version != formatVersion {
+		return nil, fmt.Errorf("unsupported vault version %d", f.Version)
+	}
+
+	key, err := deriveKey(passphrase, f.KDF)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+
+	if len(f.Nonce) != aead.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+
+	additionalData, err := f.additionalData()
+	if err != nil {
+		return nil, fmt.Errorf("marshaling header: %w", err)
+	}
+
+	plaintext, err := aead.Open(nil, f.Nonce, f.Ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+
+	err = json.Unmarshal(plaintext, &secrets)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshaling secrets: %w", err)
+	}
+
+	return secrets, nil
+}
+
+// Load reads and decrypts the vault file.  It returns an empty map if the file
+// doesn't exist.
+func Load(vaultPath string, passphrase []byte) (secrets map[string]string, err error) {
+	data, err := os.ReadFile(filepath.Clean(vaultPath))
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading vault: %w", err)
+	}
+
+	return Open(data, passphrase)
+}
+
+// Save encrypts the secrets and writes them to the vault file, which is only
+// readable by the owner.  The file is replaced atomically.
+func Save(vaultPath string, passphrase []byte, secrets map[string]string) (err error) {
+	data, err := Seal(secrets, passphrase)
+	if err != nil {
+		return fmt.Errorf("sealing vault: %w", err)
+	}
+
+	dir := filepath.Dir(vaultPath)
+
+	err = os.MkdirAll(dir, 0o700)
+	if err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(dir, ".vault-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary file: %w", err)
+	}
+
+	tmpPath := tmp.Name()
+	defer func() {
+		if err != nil {
+			err = errors.WithDeferred(err, os.Remove(tmpPath))
+		}
+	}()
+
+	_, err = tmp.Write(data)
+	err = errors.WithDeferred(err, tmp.Close())
+	if err != nil {
+		return fmt.Errorf("writing temporary file: %w", err)
+	}
+
+	err = os.Rename(tmpPath, vaultPath)
+	if err != nil {
+		return fmt.Errorf("replacing vault: %w", err)
+	}
+
+	return nil
+}
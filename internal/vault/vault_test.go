@@ -0,0 +1,82 @@
+package vault_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maximtop/extdash/internal/vault"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealOpen(t *testing.T) {
+	secrets := map[string]string{
+		"CHROME_CLIENT_SECRET": "chrome secret",
+		"FIREFOX_CLIENT_ID":    "firefox id",
+	}
+
+	data, err := vault.Seal(secrets, []byte("passphrase"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "chrome secret")
+
+	t.Run("success", func(t *testing.T) {
+		opened, openErr := vault.Open(data, []byte("passphrase"))
+		require.NoError(t, openErr)
+
+		assert.Equal(t, secrets, opened)
+	})
+
+	t.Run("wrong_passphrase", func(t *testing.T) {
+		_, openErr := vault.Open(data, []byte("wrong"))
+		assert.ErrorIs(t, openErr, vault.ErrWrongPassphrase)
+	})
+
+	t.Run("tampered_header", func(t *testing.T) {
+		var f map[string]any
+		require.NoError(t, json.Unmarshal(data, &f))
+
+		f["kdf"].(map[string]any)["time"] = 1
+
+		tampered, marshalErr := json.Marshal(f)
+		require.NoError(t, marshalErr)
+
+		_, openErr := vault.Open(tampered, []byte("passphrase"))
+		assert.ErrorIs(t, openErr, vault.ErrWrongPassphrase)
+	})
+
+	t.Run("kdf_limits", func(t *testing.T) {
+		var f map[string]any
+		require.NoError(t, json.Unmarshal(data, &f))
+
+		f["kdf"].(map[string]any)["memory"] = 64 * 1024 * 1024
+
+		tampered, marshalErr := json.Marshal(f)
+		require.NoError(t, marshalErr)
+
+		_, openErr := vault.Open(tampered, []byte("passphrase"))
+		assert.ErrorContains(t, openErr, "kdf parameters exceed the limits")
+	})
+}
+
+func TestSaveLoad(t *testing.T) {
+	vaultPath := filepath.Join(t.TempDir(), "extdash", "vault.json")
+
+	secrets, err := vault.Load(vaultPath, []byte("passphrase"))
+	require.NoError(t, err)
+	assert.Empty(t, secrets)
+
+	secrets["EDGE_CLIENT_SECRET"] = "edge secret"
+
+	err = vault.Save(vaultPath, []byte("passphrase"), secrets)
+	require.NoError(t, err)
+
+	info, err := os.Stat(vaultPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	loaded, err := vault.Load(vaultPath, []byte("passphrase"))
+	require.NoError(t, err)
+	assert.Equal(t, secrets, loaded)
+}
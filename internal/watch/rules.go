@@ -0,0 +1,244 @@
+package watch
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maximtop/extdash/internal/notify"
+)
+
+// Rule is the alert rule.
+type Rule string
+
+// Alert rules.
+const (
+	// RuleReview fires when the pending version is in review for too long.
+	RuleReview Rule = "review"
+	// RuleDrift fires when the store serves the older version than the other
+	// stores for too long.
+	RuleDrift Rule = "drift"
+	// RuleDisabled fires when the extension is disabled or taken down.
+	RuleDisabled Rule = "disabled"
+)
+
+// Alert is the state of the alert rule for the target.
+type Alert struct {
+	// Since is the time since the condition of the rule holds.
+	Since time.Time `json:"since"`
+	// Fired is true if the alert has been sent, it isn't sent again until the
+	// condition clears.
+	Fired bool `json:"fired"`
+}
+
+// Rules are the alert rules of the extension.
+type Rules struct {
+	// Review is the time the pending version may be in review, the rule is
+	// disabled if zero.
+	Review time.Duration
+	// Drift is the grace period during which the stores may serve different
+	// versions, the rule is disabled if zero.
+	Drift time.Duration
+	// Disabled enables the alert when the extension is disabled or taken
+	// down.
+	Disabled bool
+	// Notifier receives the alerts, the notifier of the watcher is used if
+	// nil.
+	Notifier notify.Notifier
+}
+
+// condition is the condition of the rule, which holds for the target.
+type condition struct {
+	since     time.Time
+	threshold time.Duration
+	message   string
+}
+
+// newestVersion is the newest published version of the extension and the
+// store publishing it.
+type newestVersion struct {
+	version string
+	store   string
+}
+
+// conditions returns the conditions of the rules holding for the snapshot.
+func (r *Rules) conditions(s *Snapshot, newest newestVersion, now time.Time) (conds map[Rule]condition) {
+	conds = map[Rule]condition{}
+
+	if r.Review > 0 && s.State == StateInReview {
+		conds[RuleReview] = condition{
+			since:     s.ChangedAt,
+			threshold: r.Review,
+			message:   fmt.Sprintf("in review since %s", s.ChangedAt.Format(time.RFC3339)),
+		}
+	}
+
+	if r.Drift > 0 && s.Version != "" && CompareVersions(s.Version, newest.version) < 0 {
+		conds[RuleDrift] = condition{
+			since:     now,
+			threshold: r.Drift,
+			message:   fmt.Sprintf("version %s is published in %s", newest.version, newest.store),
+		}
+	}
+
+	if r.Disabled && s.State == StateDisabled {
+		conds[RuleDisabled] = condition{
+			since:   s.ChangedAt,
+			message: "disabled or taken down",
+		}
+	}
+
+	return conds
+}
+
+// newestVersions returns the newest published versions by extension name.
+func (w *Watcher) newestVersions() (newest map[string]newestVersion) {
+	newest = map[string]newestVersion{}
+	for _, target := range w.Targets {
+		s, ok := w.state[target.key()]
+		if !ok || s.Version == "" {
+			continue
+		}
+
+		n, ok := newest[target.Extension]
+		if !ok || CompareVersions(s.Version, n.version) > 0 {
+			newest[target.Extension] = newestVersion{version: s.Version, store: target.Store}
+		}
+	}
+
+	return newest
+}
+
+// alertEvent is the alert and the notifier it is routed to.
+type alertEvent struct {
+	event    *notify.Event
+	notifier notify.Notifier
+	// alert is the state of the alert, which is marked as fired after the
+	// event is sent.
+	alert *Alert
+}
+
+// evaluateRules updates the state of the alerts and returns the alerts, which
+// conditions have lasted longer than their thresholds and which haven't been
+// sent yet.  The caller marks the alerts as fired.  It must be called with the
+// lock held.
+func (w *Watcher) evaluateRules(now time.Time) (alerts []alertEvent) {
+	newest := w.newestVersions()
+
+	for _, target := range w.Targets {
+		rules := w.Rules[target.Extension]
+		s, ok := w.state[target.key()]
+		if rules == nil || !ok {
+			continue
+		}
+
+		conds := rules.conditions(s, newest[target.Extension], now)
+		for rule := range s.Alerts {
+			if _, ok = conds[rule]; !ok {
+				delete(s.Alerts, rule)
+			}
+		}
+
+		for rule, cond := range conds {
+			a, ok := s.Alerts[rule]
+			if !ok {
+				a = &Alert{Since: cond.since}
+				if s.Alerts == nil {
+					s.Alerts = map[Rule]*Alert{}
+				}
+
+				s.Alerts[rule] = a
+			}
+
+			if a.Fired || now.Sub(a.Since) < cond.threshold {
+				continue
+			}
+
+			notifier := rules.Notifier
+			if notifier == nil {
+				notifier = w.Notifier
+			}
+
+			alerts = append(alerts, alertEvent{
+				event: &notify.Event{
+					Time:           now,
+					Type:           notify.EventAlert,
+					Rule:           string(rule),
+					Extension:      target.Extension,
+					Store:          target.Store,
+					AppID:          target.AppID,
+					Version:        s.Version,
+					PendingVersion: s.PendingVersion,
+					State:          string(s.State),
+					Message:        cond.message,
+				},
+				notifier: notifier,
+				alert:    a,
+			})
+		}
+	}
+
+	return alerts
+}
+
+// CompareVersions compares the dot-separated versions of the extensions and
+// returns -1, 0 or 1 if a is older, the same or newer than b.  The numeric
+// parts are compared as numbers, the version with the suffix, e.g. "1.0b1",
+// is older than the version without it.
+func CompareVersions(a, b string) (res int) {
+	aParts, bParts := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		res = comparePart(versionPart(aParts, i), versionPart(bParts, i))
+		if res != 0 {
+			return res
+		}
+	}
+
+	return 0
+}
+
+// versionPart returns the i-th part of the version, the missing parts are
+// zero.
+func versionPart(parts []string, i int) (part string) {
+	if i < len(parts) {
+		return parts[i]
+	}
+
+	return "0"
+}
+
+// comparePart compares the parts of the versions.
+func comparePart(a, b string) (res int) {
+	aNum, aSuffix := splitNumber(a)
+	bNum, bSuffix := splitNumber(b)
+
+	switch {
+	case aNum < bNum:
+		return -1
+	case aNum > bNum:
+		return 1
+	case aSuffix == bSuffix:
+		return 0
+	case aSuffix == "":
+		return 1
+	case bSuffix == "":
+		return -1
+	default:
+		return strings.Compare(aSuffix, bSuffix)
+	}
+}
+
+// splitNumber splits the part of the version into the leading number and the
+// suffix.
+func splitNumber(part string) (num uint64, suffix string) {
+	i := 0
+	for i < len(part) && part[i] >= '0' && part[i] <= '9' {
+		i++
+	}
+
+	// the overflow is treated as zero, the versions aren't that long
+	num, _ = strconv.ParseUint(part[:i], 10, 64)
+
+	return num, part[i:]
+}
@@ -0,0 +1,178 @@
+package watch_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/maximtop/extdash/internal/notify"
+	"github.com/maximtop/extdash/internal/watch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// staticSource returns the copy of the snapshot, which can be changed between
+// the checks.
+type staticSource struct {
+	snapshot watch.Snapshot
+}
+
+func (s *staticSource) Snapshot(_ string) (snapshot *watch.Snapshot, err error) {
+	snapshot = &watch.Snapshot{}
+	*snapshot = s.snapshot
+
+	return snapshot, nil
+}
+
+// alertRules returns the rules of the alert events.
+func alertRules(events []*notify.Event) (rules []string) {
+	for _, e := range events {
+		if e.Type == notify.EventAlert {
+			rules = append(rules, e.Store+" "+e.Rule)
+		}
+	}
+
+	return rules
+}
+
+func TestWatcher_Check_rules(t *testing.T) {
+	chrome := &staticSource{snapshot: watch.Snapshot{State: watch.StatePublished, Version: "1.1.0"}}
+	firefox := &staticSource{snapshot: watch.Snapshot{
+		State:          watch.StateInReview,
+		Version:        "1.0.0",
+		PendingVersion: "1.1.0",
+	}}
+
+	routed := &notifierStub{}
+	notifier := &notifierStub{}
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	w := &watch.Watcher{
+		Targets: []*watch.Target{{
+			Extension: "test",
+			Store:     "chrome",
+			AppID:     "test",
+			Source:    chrome,
+		}, {
+			Extension: "test",
+			Store:     "firefox",
+			AppID:     "test@example.org",
+			Source:    firefox,
+		}},
+		Notifier: notifier,
+		Rules: map[string]*watch.Rules{
+			"test": {
+				Review:   48 * time.Hour,
+				Drift:    24 * time.Hour,
+				Disabled: true,
+				Notifier: routed,
+			},
+		},
+		Now: func() time.Time { return now },
+	}
+
+	check := func() (rules []string) {
+		events, err := w.Check(context.Background())
+		require.NoError(t, err)
+
+		return alertRules(events)
+	}
+
+	assert.Empty(t, check())
+
+	now = now.Add(24 * time.Hour)
+	assert.Equal(t, []string{"firefox drift"}, check())
+
+	// the alert isn't repeated while the condition holds
+	now = now.Add(24 * time.Hour)
+	assert.Equal(t, []string{"firefox review"}, check())
+
+	// the alerts are cleared when the version is published
+	firefox.snapshot = watch.Snapshot{State: watch.StatePublished, Version: "1.1.0"}
+	now = now.Add(time.Hour)
+	assert.Empty(t, check())
+	assert.Empty(t, w.Snapshots()["firefox/test@example.org"].Alerts)
+
+	chrome.snapshot = watch.Snapshot{State: watch.StateDisabled, Version: "1.1.0"}
+	now = now.Add(time.Hour)
+	assert.Equal(t, []string{"chrome disabled"}, check())
+
+	assert.Equal(t, []string{"firefox drift", "firefox review", "chrome disabled"}, alertRules(routed.events))
+	assert.Empty(t, alertRules(notifier.events))
+}
+
+func TestCompareVersions(t *testing.T) {
+	testCases := []struct {
+		a    string
+		b    string
+		want int
+	}{{
+		a:    "1.0.0",
+		b:    "1.0.0",
+		want: 0,
+	}, {
+		a:    "1.0",
+		b:    "1.0.0",
+		want: 0,
+	}, {
+		a:    "1.2.10",
+		b:    "1.2.9",
+		want: 1,
+	}, {
+		a:    "1.0.0b1",
+		b:    "1.0.0",
+		want: -1,
+	}, {
+		a:    "1.0.0b2",
+		b:    "1.0.0b1",
+		want: 1,
+	}, {
+		a:    "4.1.53.1",
+		b:    "4.2",
+		want: -1,
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.a+"_"+tc.b, func(t *testing.T) {
+			assert.Equal(t, tc.want, watch.CompareVersions(tc.a, tc.b))
+		})
+	}
+}
+
+func TestWatcher_Check_rulesNotifyError(t *testing.T) {
+	routed := &notifierStub{err: assert.AnError}
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	w := &watch.Watcher{
+		Targets: []*watch.Target{{
+			Extension: "test",
+			Store:     "chrome",
+			AppID:     "test",
+			Source:    &staticSource{snapshot: watch.Snapshot{State: watch.StateDisabled}},
+		}},
+		Rules: map[string]*watch.Rules{
+			"test": {Disabled: true, Notifier: routed},
+		},
+		Now: func() time.Time { return now },
+	}
+
+	_, err := w.Check(context.Background())
+	require.ErrorIs(t, err, assert.AnError)
+	assert.False(t, w.Snapshots()["chrome/test"].Alerts[watch.RuleDisabled].Fired)
+
+	// the alert is sent again once the notifier works
+	routed.err = nil
+	now = now.Add(time.Hour)
+
+	events, err := w.Check(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"chrome disabled"}, alertRules(events))
+	assert.True(t, w.Snapshots()["chrome/test"].Alerts[watch.RuleDisabled].Fired)
+
+	now = now.Add(time.Hour)
+
+	events, err = w.Check(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, alertRules(events))
+	assert.Len(t, routed.events, 1)
+}
@@ -0,0 +1,138 @@
+package watch
+
+import (
+	"fmt"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/maximtop/extdash/internal/chrome"
+	"github.com/maximtop/extdash/internal/firefox"
+)
+
+// ChromeSource retrieves the status from the Chrome Web Store API v1.  The API
+// v1 doesn't report the review state, so the unpublished draft is reported as
+// StateUploaded.
+type ChromeSource struct {
+	Store *chrome.Store
+}
+
+// type check
+var _ Source = (*ChromeSource)(nil)
+
+// Snapshot implements the Source interface for *ChromeSource.
+func (s *ChromeSource) Snapshot(appID string) (snapshot *Snapshot, err error) {
+	versions, err := s.Store.Versions(appID)
+	if err != nil {
+		return nil, fmt.Errorf("getting versions: %w", err)
+	}
+
+	snapshot = &Snapshot{
+		State:   StatePublished,
+		Version: versions.Published.CrxVersion,
+	}
+
+	if versions.Draft.CrxVersion != "" && !versions.Live() {
+		snapshot.State = StateUploaded
+		snapshot.PendingVersion = versions.Draft.CrxVersion
+	}
+
+	return snapshot, nil
+}
+
+// ChromeV2Source retrieves the status from the Chrome Web Store API v2.
+type ChromeV2Source struct {
+	Store *chrome.StoreV2
+}
+
+// type check
+var _ Source = (*ChromeV2Source)(nil)
+
+// chromeV2States maps the states of the submitted revision to the states.
+var chromeV2States = map[chrome.ItemState]State{
+	chrome.ItemStatePendingReview: StateInReview,
+	chrome.ItemStateStaged:        StateStaged,
+	chrome.ItemStateRejected:      StateRejected,
+}
+
+// Snapshot implements the Source interface for *ChromeV2Source.
+func (s *ChromeV2Source) Snapshot(appID string) (snapshot *Snapshot, err error) {
+	status, err := s.Store.Status(appID)
+	if err != nil {
+		return nil, fmt.Errorf("getting status: %w", err)
+	}
+
+	snapshot = &Snapshot{
+		State:   StatePublished,
+		Version: status.PublishedItemRevisionStatus.CrxVersion(),
+	}
+
+	if status.TakenDown {
+		snapshot.State = StateDisabled
+
+		return snapshot, nil
+	}
+
+	submitted := status.SubmittedItemRevisionStatus
+	if submitted == nil {
+		return snapshot, nil
+	}
+
+	if state, ok := chromeV2States[submitted.State]; ok {
+		snapshot.State = state
+		snapshot.PendingVersion = submitted.CrxVersion()
+	}
+
+	return snapshot, nil
+}
+
+// FirefoxSource retrieves the status from the AMO API.  Only the listed
+// versions are taken into account.
+type FirefoxSource struct {
+	Store *firefox.Store
+}
+
+// type check
+var _ Source = (*FirefoxSource)(nil)
+
+// Snapshot implements the Source interface for *FirefoxSource.
+func (s *FirefoxSource) Snapshot(appID string) (snapshot *Snapshot, err error) {
+	addon, err := s.Store.Addon(appID)
+	if err != nil {
+		return nil, fmt.Errorf("getting add-on: %w", err)
+	}
+
+	snapshot = &Snapshot{
+		State: StatePublished,
+	}
+
+	if addon.CurrentVersion != nil {
+		snapshot.Version = addon.CurrentVersion.Version
+	}
+
+	if addon.Disabled() {
+		snapshot.State = StateDisabled
+
+		return snapshot, nil
+	}
+
+	latest, err := s.Store.LatestListedVersion(appID)
+	if errors.Is(err, firefox.ErrVersionNotFound) {
+		return snapshot, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("getting latest version: %w", err)
+	}
+
+	if latest.Version == snapshot.Version {
+		return snapshot, nil
+	}
+
+	switch latest.FileStatus() {
+	case firefox.FileStatusUnreviewed:
+		snapshot.State = StateInReview
+		snapshot.PendingVersion = latest.Version
+	case firefox.FileStatusDisabled:
+		snapshot.State = StateRejected
+		snapshot.PendingVersion = latest.Version
+	}
+
+	return snapshot, nil
+}
@@ -0,0 +1,334 @@
+// Package watch contains the watcher, which polls the status of the extensions
+// in the stores and notifies about the changes.
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/AdguardTeam/golibs/errors"
+	"github.com/AdguardTeam/golibs/log"
+	"github.com/maximtop/extdash/internal/notify"
+)
+
+// DefaultInterval is the default interval between the status checks.
+const DefaultInterval = 10 * time.Minute
+
+// State is the state of the extension in the store.
+type State string
+
+// Extension states.
+const (
+	// StateUploaded means that the new version is uploaded, but isn't
+	// submitted for review yet, or the store doesn't report the review state.
+	StateUploaded State = "uploaded"
+	// StateInReview means that the new version awaits review.
+	StateInReview State = "in_review"
+	// StateStaged means that the new version is approved and awaits manual
+	// publishing.
+	StateStaged State = "staged"
+	// StatePublished means that the latest version is published.
+	StatePublished State = "published"
+	// StateRejected means that the new version is rejected by the store.
+	StateRejected State = "rejected"
+	// StateDisabled means that the extension is disabled or taken down.
+	StateDisabled State = "disabled"
+)
+
+// Snapshot describes the status of the extension in the store at some moment.
+type Snapshot struct {
+	CheckedAt time.Time `json:"checked_at"`
+	// ChangedAt is the time when the state was observed first.
+	ChangedAt time.Time `json:"changed_at"`
+	State     State     `json:"state"`
+	// Version is the published version.
+	Version string `json:"version,omitempty"`
+	// PendingVersion is the uploaded version which isn't published yet.
+	PendingVersion string `json:"pending_version,omitempty"`
+	// Alerts are the states of the alert rules, which conditions hold.
+	Alerts map[Rule]*Alert `json:"alerts,omitempty"`
+}
+
+// changed returns true if the state or the versions differ.
+func (s *Snapshot) changed(other *Snapshot) (ok bool) {
+	return s.State != other.State || s.Version != other.Version || s.PendingVersion != other.PendingVersion
+}
+
+// Source retrieves the status of the extension from the store.
+type Source interface {
+	Snapshot(appID string) (snapshot *Snapshot, err error)
+}
+
+// Target is the extension in the store to watch.
+type Target struct {
+	// Extension is the name of the extension, which is the same across the
+	// stores.
+	Extension string
+	Store     string
+	AppID     string
+	Source    Source
+}
+
+// key returns the key of the target in the state.
+func (t *Target) key() (key string) {
+	return t.Store + "/" + t.AppID
+}
+
+// Watcher polls the status of the targets and notifies about the changes.  The
+// last known snapshots are persisted to StatePath, so the changes which happen
+// between the runs are reported as well.
+type Watcher struct {
+	Targets  []*Target
+	Notifier notify.Notifier
+	// StatePath is the path to the file with the last known snapshots, the
+	// state isn't persisted if empty.
+	StatePath string
+	// Interval is the interval between the checks, DefaultInterval is used if
+	// zero.
+	Interval time.Duration
+	// Rules are the alert rules by extension name, the alerts are checked
+	// after every check of the targets.
+	Rules map[string]*Rules
+	// Now returns the current time, time.Now is used if nil.
+	Now func() time.Time
+
+	mu    sync.Mutex
+	state map[string]*Snapshot
+}
+
+// now returns the current time.
+func (w *Watcher) now() (t time.Time) {
+	if w.Now != nil {
+		return w.Now()
+	}
+
+	return time.Now()
+}
+
+// LoadState reads the snapshots persisted by the watcher.  It returns an empty
+// map if the file doesn't exist.
+func LoadState(statePath string) (state map[string]*Snapshot, err error) {
+	state = map[string]*Snapshot{}
+
+	data, err := os.ReadFile(filepath.Clean(statePath))
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading state: %w", err)
+	}
+
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return nil, fmt.Errorf("unmarshaling state: %w", err)
+	}
+
+	return state, nil
+}
+
+// saveState writes the snapshots to the file.
+func saveState(statePath string, state map[string]*Snapshot) (err error) {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling state: %w", err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(statePath), 0o700)
+	if err != nil {
+		return fmt.Errorf("creating directory: %w", err)
+	}
+
+	tmpPath := statePath + ".tmp"
+
+	err = os.WriteFile(tmpPath, data, 0o600)
+	if err != nil {
+		return fmt.Errorf("writing state: %w", err)
+	}
+
+	err = os.Rename(tmpPath, statePath)
+	if err != nil {
+		return fmt.Errorf("replacing state: %w", err)
+	}
+
+	return nil
+}
+
+// Snapshots returns the copy of the last known snapshots by target key, e.g.
+// "firefox/extension@example.org".
+func (w *Watcher) Snapshots() (snapshots map[string]Snapshot) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	snapshots = make(map[string]Snapshot, len(w.state))
+	for key, snapshot := range w.state {
+		c := *snapshot
+		if snapshot.Alerts != nil {
+			c.Alerts = make(map[Rule]*Alert, len(snapshot.Alerts))
+			for rule, a := range snapshot.Alerts {
+				alert := *a
+				c.Alerts[rule] = &alert
+			}
+		}
+
+		snapshots[key] = c
+	}
+
+	return snapshots
+}
+
+// Check retrieves the status of every target once, notifies about the changes
+// since the previous check, evaluates the alert rules and persists the state.
+// The first snapshot of the target is recorded without notification, and the
+// changed one is recorded only after the notification is sent, so the failed
+// notification is retried on the next check.  The errors of the targets don't
+// stop the check, they are returned joined.
+func (w *Watcher) Check(ctx context.Context) (events []*notify.Event, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.state == nil {
+		w.state = map[string]*Snapshot{}
+		if w.StatePath != "" {
+			w.state, err = LoadState(w.StatePath)
+			if err != nil {
+				return nil, fmt.Errorf("loading state: %w", err)
+			}
+		}
+	}
+
+	now := w.now()
+
+	var errs []error
+	for _, target := range w.Targets {
+		current, event, checkErr := w.checkTarget(target, now)
+		if checkErr != nil {
+			errs = append(errs, fmt.Errorf("%s (%s): %w", target.Extension, target.Store, checkErr))
+
+			continue
+		}
+
+		if event == nil {
+			continue
+		}
+
+		events = append(events, event)
+
+		if w.Notifier != nil {
+			notifyErr := w.Notifier.Notify(ctx, event)
+			if notifyErr != nil {
+				// keep the previous snapshot, so that the change is detected
+				// and notified about again on the next check
+				errs = append(errs, fmt.Errorf("notifying about %s: %w", event.Summary(), notifyErr))
+
+				continue
+			}
+		}
+
+		w.state[target.key()] = current
+	}
+
+	for _, alert := range w.evaluateRules(now) {
+		events = append(events, alert.event)
+
+		if alert.notifier != nil {
+			notifyErr := alert.notifier.Notify(ctx, alert.event)
+			if notifyErr != nil {
+				// the alert isn't marked as fired, so it's sent again on
+				// the next check
+				errs = append(errs, fmt.Errorf("notifying about %s: %w", alert.event.Summary(), notifyErr))
+
+				continue
+			}
+		}
+
+		alert.alert.Fired = true
+	}
+
+	if w.StatePath != "" {
+		saveErr := saveState(w.StatePath, w.state)
+		if saveErr != nil {
+			errs = append(errs, fmt.Errorf("saving state: %w", saveErr))
+		}
+	}
+
+	if len(errs) > 0 {
+		return events, errors.List("checking targets", errs...)
+	}
+
+	return events, nil
+}
+
+// checkTarget retrieves the snapshot of the target and returns the event if it
+// differs from the previous one.  The snapshot is stored in the state unless
+// the event is returned, the caller stores it after the event is delivered.
+func (w *Watcher) checkTarget(
+	target *Target,
+	now time.Time,
+) (current *Snapshot, event *notify.Event, err error) {
+	current, err = target.Source.Snapshot(target.AppID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting snapshot: %w", err)
+	}
+
+	current.CheckedAt = now
+	current.ChangedAt = now
+
+	key := target.key()
+	previous, ok := w.state[key]
+	if !ok {
+		log.Debug("watch: %s: initial state %s", key, current.State)
+		w.state[key] = current
+
+		return current, nil, nil
+	}
+
+	current.Alerts = previous.Alerts
+
+	if !current.changed(previous) {
+		current.ChangedAt = previous.ChangedAt
+		w.state[key] = current
+
+		return current, nil, nil
+	}
+
+	return current, &notify.Event{
+		Time:           now,
+		Type:           notify.EventStatusChanged,
+		Extension:      target.Extension,
+		Store:          target.Store,
+		AppID:          target.AppID,
+		Version:        current.Version,
+		PendingVersion: current.PendingVersion,
+		PreviousState:  string(previous.State),
+		State:          string(current.State),
+	}, nil
+}
+
+// Run checks the targets every Interval until ctx is done.  The errors of the
+// checks are logged.
+func (w *Watcher) Run(ctx context.Context) (err error) {
+	interval := w.Interval
+	if interval == 0 {
+		interval = DefaultInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_, err = w.Check(ctx)
+		if err != nil {
+			log.Error("watch: %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package watch_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/maximtop/extdash/internal/firefox"
+	"github.com/maximtop/extdash/internal/notify"
+	"github.com/maximtop/extdash/internal/watch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sourceStub returns the snapshots from the list one by one.
+type sourceStub struct {
+	snapshots []*watch.Snapshot
+	err       error
+}
+
+func (s *sourceStub) Snapshot(_ string) (snapshot *watch.Snapshot, err error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	snapshot, s.snapshots = s.snapshots[0], s.snapshots[1:]
+
+	return snapshot, nil
+}
+
+// notifierStub records the events, it fails while err is set.
+type notifierStub struct {
+	events []*notify.Event
+	err    error
+}
+
+func (n *notifierStub) Notify(_ context.Context, event *notify.Event) (err error) {
+	if n.err != nil {
+		return n.err
+	}
+
+	n.events = append(n.events, event)
+
+	return nil
+}
+
+func TestWatcher_Check(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+
+	source := &sourceStub{snapshots: []*watch.Snapshot{{
+		State:   watch.StatePublished,
+		Version: "1.0.0",
+	}, {
+		State:          watch.StateInReview,
+		Version:        "1.0.0",
+		PendingVersion: "1.0.1",
+	}, {
+		State:          watch.StateInReview,
+		Version:        "1.0.0",
+		PendingVersion: "1.0.1",
+	}, {
+		State:   watch.StatePublished,
+		Version: "1.0.1",
+	}}}
+
+	notifier := &notifierStub{}
+
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	newWatcher := func() *watch.Watcher {
+		return &watch.Watcher{
+			Targets: []*watch.Target{{
+				Extension: "test",
+				Store:     "firefox",
+				AppID:     "test@example.org",
+				Source:    source,
+			}},
+			Notifier:  notifier,
+			StatePath: statePath,
+			Now: func() time.Time {
+				now = now.Add(time.Hour)
+
+				return now
+			},
+		}
+	}
+
+	w := newWatcher()
+
+	// the initial state is recorded without notification
+	events, err := w.Check(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	events, err = w.Check(context.Background())
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "published", events[0].PreviousState)
+	assert.Equal(t, "in_review", events[0].State)
+	assert.Equal(t, "1.0.1", events[0].PendingVersion)
+
+	events, err = w.Check(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, events)
+
+	snapshot := w.Snapshots()["firefox/test@example.org"]
+	assert.Equal(t, time.Date(2022, 1, 1, 2, 0, 0, 0, time.UTC), snapshot.ChangedAt)
+	assert.Equal(t, time.Date(2022, 1, 1, 3, 0, 0, 0, time.UTC), snapshot.CheckedAt)
+
+	// the new watcher continues from the persisted state
+	w = newWatcher()
+
+	events, err = w.Check(context.Background())
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "in_review", events[0].PreviousState)
+	assert.Equal(t, "published", events[0].State)
+	assert.Equal(t, "1.0.1", events[0].Version)
+
+	assert.Len(t, notifier.events, 2)
+}
+
+func TestWatcher_Check_error(t *testing.T) {
+	w := &watch.Watcher{
+		Targets: []*watch.Target{{
+			Extension: "broken",
+			Store:     "chrome",
+			AppID:     "broken",
+			Source:    &sourceStub{err: fmt.Errorf("test error")},
+		}, {
+			Extension: "test",
+			Store:     "chrome",
+			AppID:     "test",
+			Source:    &sourceStub{snapshots: []*watch.Snapshot{{State: watch.StatePublished}}},
+		}},
+	}
+
+	_, err := w.Check(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "test error")
+
+	assert.Contains(t, w.Snapshots(), "chrome/test")
+}
+
+func TestWatcher_Check_notifyError(t *testing.T) {
+	inReview := &watch.Snapshot{State: watch.StateInReview, Version: "1.0.0", PendingVersion: "1.0.1"}
+
+	notifier := &notifierStub{err: fmt.Errorf("test error")}
+	w := &watch.Watcher{
+		Targets: []*watch.Target{{
+			Extension: "test",
+			Store:     "chrome",
+			AppID:     "test",
+			Source: &sourceStub{snapshots: []*watch.Snapshot{
+				{State: watch.StatePublished, Version: "1.0.0"},
+				inReview,
+				inReview,
+			}},
+		}},
+		Notifier: notifier,
+	}
+
+	_, err := w.Check(context.Background())
+	require.NoError(t, err)
+
+	_, err = w.Check(context.Background())
+	assert.ErrorContains(t, err, "test error")
+	assert.Equal(t, watch.StatePublished, w.Snapshots()["chrome/test"].State)
+
+	// the change is detected again once the notifier works
+	notifier.err = nil
+
+	events, err := w.Check(context.Background())
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, "published", events[0].PreviousState)
+	assert.Equal(t, "in_review", events[0].State)
+	assert.Equal(t, watch.StateInReview, w.Snapshots()["chrome/test"].State)
+	assert.Len(t, notifier.events, 1)
+}
+
+func TestFirefoxSource_Snapshot(t *testing.T) {
+	testCases := []struct {
+		name        string
+		addon       string
+		latest      string
+		wantState   watch.State
+		wantVersion string
+		wantPending string
+	}{{
+		name:        "published",
+		addon:       `{"status": "public", "current_version": {"version": "1.0.0"}}`,
+		latest:      `{"results": [{"version": "1.0.0", "file": {"status": "public"}}]}`,
+		wantState:   watch.StatePublished,
+		wantVersion: "1.0.0",
+	}, {
+		name:        "in_review",
+		addon:       `{"status": "public", "current_version": {"version": "1.0.0"}}`,
+		latest:      `{"results": [{"version": "1.0.1", "file": {"status": "unreviewed"}}]}`,
+		wantState:   watch.StateInReview,
+		wantVersion: "1.0.0",
+		wantPending: "1.0.1",
+	}, {
+		name:        "rejected",
+		addon:       `{"status": "public", "current_version": {"version": "1.0.0"}}`,
+		latest:      `{"results": [{"version": "1.0.1", "file": {"status": "disabled"}}]}`,
+		wantState:   watch.StateRejected,
+		wantVersion: "1.0.0",
+		wantPending: "1.0.1",
+	}, {
+		name:        "disabled",
+		addon:       `{"status": "disabled", "current_version": {"version": "1.0.0"}}`,
+		wantState:   watch.StateDisabled,
+		wantVersion: "1.0.0",
+	}}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/api/v5/addons/addon/test/":
+					_, _ = w.Write([]byte(tc.addon))
+				case "/api/v5/addons/addon/test/versions/":
+					assert.Equal(t, "all_without_unlisted", r.URL.Query().Get("filter"))
+					_, _ = w.Write([]byte(tc.latest))
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer server.Close()
+
+			storeURL, err := url.Parse(server.URL)
+			require.NoError(t, err)
+
+			client := firefox.NewClient(firefox.ClientConfig{ClientID: "id", ClientSecret: "secret"})
+			source := &watch.FirefoxSource{Store: &firefox.Store{Client: &client, URL: storeURL}}
+
+			snapshot, err := source.Snapshot("test")
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.wantState, snapshot.State)
+			assert.Equal(t, tc.wantVersion, snapshot.Version)
+			assert.Equal(t, tc.wantPending, snapshot.PendingVersion)
+		})
+	}
+}